@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"adapter/internal/shared/lock"
+	"adapter/internal/shared/log"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Job is a named task the cron process can schedule or run on demand.
+type Job struct {
+	Name        string
+	Description string
+	Spec        string
+	Run         func(ctx context.Context) error
+
+	running atomic.Bool
+	locker  *lock.Locker
+	lockTTL time.Duration
+	// period is the shortest gap between scheduled runs; it is zero for jobs run on demand.
+	period time.Duration
+}
+
+var (
+	// ErrJobAlreadyRunning is returned when a job is triggered while its previous run is still going.
+	ErrJobAlreadyRunning = errors.New("job is already running")
+	// ErrJobLocked is returned when another replica is running the job.
+	ErrJobLocked = errors.New("job is running on another replica")
+	// ErrJobRanThisPeriod is returned when a replica already started the job in the current period.
+	ErrJobRanThisPeriod = errors.New("job already ran in this period")
+)
+
+// periodSlack keeps a period marker from outliving the period, so that the replica which set it
+// is never blocked by its own marker on its next tick.
+const periodSlack = time.Second
+
+// Execute runs the job unless a previous run of it is still in progress, here or, when a
+// locker is configured, on another replica. A scheduled job also leaves a marker for its period,
+// so each replica's own schedule does not start it again until the period has passed. A failed
+// run removes its marker so that the next tick retries it.
+func (j *Job) Execute(ctx context.Context) error {
+	if !j.running.CompareAndSwap(false, true) {
+		log.Warnf(ctx, "Skipping %s: previous run is still in progress", j.Name)
+		return ErrJobAlreadyRunning
+	}
+	defer j.running.Store(false)
+
+	var periodMarker *lock.Lock
+	if j.locker != nil {
+		held, err := j.locker.Acquire(ctx, "cron:"+j.Name, j.lockTTL)
+		if errors.Is(err, lock.ErrNotAcquired) {
+			log.Infof(ctx, "Skipping %s: another replica holds its lock", j.Name)
+			return ErrJobLocked
+		}
+		if err != nil {
+			log.Errorf(ctx, err, "Failed to acquire lock for %s", j.Name)
+			return err
+		}
+		defer func() {
+			if err := held.Release(context.Background()); err != nil {
+				log.Errorf(ctx, err, "Failed to release lock for %s", j.Name)
+			}
+		}()
+
+		if j.period > periodSlack {
+			// After a successful run the marker is left to expire just before this replica's next tick.
+			marker, err := j.locker.Mark(ctx, "cron:"+j.Name+":period", j.period-periodSlack)
+			if errors.Is(err, lock.ErrNotAcquired) {
+				log.Infof(ctx, "Skipping %s: it already ran in this period", j.Name)
+				return ErrJobRanThisPeriod
+			}
+			if err != nil {
+				log.Errorf(ctx, err, "Failed to mark the period of %s", j.Name)
+				return err
+			}
+			periodMarker = marker
+		}
+
+		// Losing the lock means another replica may start the job, so this run is stopped.
+		runCtx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+		lost := held.KeepAlive(runCtx)
+		go func() {
+			select {
+			case <-lost:
+				log.Warnf(ctx, "Lost lock for %s while it was running; cancelling the run", j.Name)
+				cancel(lock.ErrLockLost)
+			case <-runCtx.Done():
+			}
+		}()
+		ctx = runCtx
+	}
+
+	log.Infof(ctx, "Starting %s job...", j.Name)
+	start := time.Now()
+	err := j.Run(ctx)
+	if cause := context.Cause(ctx); err == nil && errors.Is(cause, lock.ErrLockLost) {
+		err = cause
+	}
+	if err != nil {
+		log.Errorf(ctx, err, "%s job failed after %s", j.Name, time.Since(start))
+		if periodMarker != nil {
+			if err := periodMarker.Release(context.Background()); err != nil && !errors.Is(err, lock.ErrLockLost) {
+				log.Errorf(ctx, err, "Failed to clear the period marker of %s", j.Name)
+			}
+		}
+		return err
+	}
+	log.Infof(ctx, "%s job completed successfully in %s", j.Name, time.Since(start))
+	return nil
+}
+
+// Registry holds the jobs known to the cron process. With a locker, each job takes a
+// distributed lock for the duration of its run so only one replica executes it, and scheduled
+// jobs run once per period however many replicas schedule them.
+type Registry struct {
+	jobs    map[string]*Job
+	locker  *lock.Locker
+	lockTTL time.Duration
+}
+
+func NewRegistry(locker *lock.Locker, lockTTL time.Duration) *Registry {
+	return &Registry{jobs: make(map[string]*Job), locker: locker, lockTTL: lockTTL}
+}
+
+func (r *Registry) Register(job *Job) {
+	job.locker = r.locker
+	job.lockTTL = r.lockTTL
+	r.jobs[job.Name] = job
+}
+
+func (r *Registry) Get(name string) (*Job, bool) {
+	job, ok := r.jobs[name]
+	return job, ok
+}
+
+// Jobs returns the registered jobs sorted by name.
+func (r *Registry) Jobs() []*Job {
+	jobs := make([]*Job, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+	return jobs
+}
+
+// Schedule adds every job with a spec to the scheduler. An empty spec disables a job.
+func (r *Registry) Schedule(ctx context.Context, c *cron.Cron) error {
+	for _, job := range r.Jobs() {
+		if job.Spec == "" {
+			log.Infof(ctx, "Job %s has no schedule and will not run", job.Name)
+			continue
+		}
+		job := job
+		schedule, err := cron.ParseStandard(job.Spec)
+		if err != nil {
+			return fmt.Errorf("invalid schedule %q for job %s: %w", job.Spec, job.Name, err)
+		}
+		job.period = shortestPeriod(schedule, time.Now())
+		c.Schedule(schedule, cron.FuncJob(func() { job.Execute(ctx) }))
+		log.Infof(ctx, "Scheduled job %s with spec %q", job.Name, job.Spec)
+	}
+	return nil
+}
+
+// shortestPeriod returns the smallest gap between the next few runs of schedule, so that
+// irregular schedules such as "0 9,17 * * *" get the period of their closest runs.
+func shortestPeriod(schedule cron.Schedule, from time.Time) time.Duration {
+	var shortest time.Duration
+	previous := schedule.Next(from)
+	for i := 0; i < 8; i++ {
+		next := schedule.Next(previous)
+		if gap := next.Sub(previous); shortest == 0 || gap < shortest {
+			shortest = gap
+		}
+		previous = next
+	}
+	return shortest
+}
+
+// runNowFlag backs -run-now. Given without a value it selects every job,
+// -run-now=<job>[,<job>] selects specific jobs.
+type runNowFlag struct {
+	all  bool
+	jobs []string
+}
+
+func (f *runNowFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	if f.all {
+		return "true"
+	}
+	return strings.Join(f.jobs, ",")
+}
+
+func (f *runNowFlag) Set(value string) error {
+	switch value {
+	case "true", "all":
+		f.all = true
+	case "false", "":
+		f.all = false
+		f.jobs = nil
+	default:
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				f.jobs = append(f.jobs, name)
+			}
+		}
+	}
+	return nil
+}
+
+func (f *runNowFlag) IsBoolFlag() bool { return true }
+
+func (f *runNowFlag) enabled() bool {
+	return f.all || len(f.jobs) > 0
+}
+
+// selected resolves the jobs to run now, failing on unknown names.
+func (f *runNowFlag) selected(r *Registry) ([]*Job, error) {
+	if f.all {
+		return r.Jobs(), nil
+	}
+	jobs := make([]*Job, 0, len(f.jobs))
+	for _, name := range f.jobs {
+		job, ok := r.Get(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown job %q", name)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
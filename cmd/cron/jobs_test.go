@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+func TestShortestPeriod(t *testing.T) {
+	from := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Duration
+	}{
+		{"@every 30m", 30 * time.Minute},
+		{"@daily", 24 * time.Hour},
+		{"0 9,17 * * *", 8 * time.Hour},
+		{"*/15 * * * *", 15 * time.Minute},
+	}
+	for _, tt := range tests {
+		schedule, err := cron.ParseStandard(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseStandard(%q): %v", tt.spec, err)
+		}
+		if got := shortestPeriod(schedule, from); got != tt.want {
+			t.Errorf("shortestPeriod(%q) = %s, want %s", tt.spec, got, tt.want)
+		}
+	}
+}
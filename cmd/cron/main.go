@@ -2,21 +2,32 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
 
 	"adapter/internal/config"
+	broadcastDomain "adapter/internal/domain/broadcast"
+	buyerDomain "adapter/internal/domain/buyer"
 	sellerDomain "adapter/internal/domain/seller"
+	buyerPorts "adapter/internal/ports/buyer"
 	sellerPorts "adapter/internal/ports/seller"
 	"adapter/internal/shared/database"
+	"adapter/internal/shared/lock"
 	"adapter/internal/shared/log"
+	redisClient "adapter/internal/shared/redis"
 
 	"github.com/joho/godotenv"
 	"github.com/robfig/cron/v3"
 )
 
 func main() {
-	// Define a command-line flag to trigger the job immediately
-	runNow := flag.Bool("run-now", false, "Run the job once immediately and exit")
+	// -run-now runs every job once and exits, -run-now=<job> runs just that job
+	var runNow runNowFlag
+	flag.Var(&runNow, "run-now", "Run all jobs, or -run-now=<job>[,<job>] the named jobs, once immediately and exit")
+	list := flag.Bool("list", false, "List the registered jobs and their schedules and exit")
 	flag.Parse()
 	ctx := context.Background()
 
@@ -34,47 +45,79 @@ func main() {
 		log.Fatal(ctx, err, "Error loading configuration")
 	}
 
+	if *list {
+		// Listing only needs the schedules, not the services behind the jobs.
+		registry := newJobRegistry(cfg, nil, nil, nil, nil)
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tSCHEDULE\tDESCRIPTION")
+		for _, job := range registry.Jobs() {
+			spec := job.Spec
+			if spec == "" {
+				spec = "(disabled)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", job.Name, spec, job.Description)
+		}
+		w.Flush()
+		return
+	}
+	if err := cfg.ValidateCron(); err != nil {
+		log.Fatal(ctx, err, "Invalid configuration")
+	}
+
 	// Initialize database
 	db, err := database.Init(cfg.DatabaseURL)
 	if err != nil {
 		log.Fatal(ctx, err, "Failed to connect to database")
 	}
 
-	// Create repository and service
+	// Create repositories and services
 	sellerRepo := sellerPorts.NewSellerRepository(db)
-	sellerService := sellerDomain.NewSellerService(sellerRepo, cfg)
-
-	// If the -run-now flag is provided, run the job once and exit
-	if *runNow {
-		log.Info(ctx, "Starting ONDC seller lookup job manually...")
-		_, err := sellerService.SyncRegistry(sellerPorts.SellerRegistrySyncRequest{
-			Domains: cfg.Domains,
-		})
-		if err != nil {
-			log.Error(ctx, err, "ONDC seller lookup cron job failed")
-		} else {
-			log.Info(ctx, "ONDC seller lookup cron job completed successfully.")
-		}
-		return
+	buyerRepo := buyerPorts.NewBuyerRepository(db)
+	sellerService := sellerDomain.NewSellerService(sellerRepo, buyerRepo, cfg)
+
+	// Refresh probes are only queued here; the API's broadcast workers send them.
+	broadcastService := broadcastDomain.NewBroadcastService(buyerRepo, sellerRepo, nil, nil, cfg)
+	buyerService := buyerDomain.NewBuyerService(buyerRepo, broadcastService, cfg)
+
+	// Jobs are guarded by a Redis lock so that cron can run on several replicas.
+	rdb, err := redisClient.Init(cfg.RedisURL)
+	if err != nil {
+		log.Fatal(ctx, err, "Failed to connect to redis")
+	}
+	defer redisClient.Close()
+	owner := cfg.WorkerID
+	if owner == "" {
+		hostname, _ := os.Hostname()
+		owner = fmt.Sprintf("%s-%d", hostname, os.Getpid())
 	}
 
-	// --- Original cron job scheduling ---
-	// Initialize cron job
-	c := cron.New()
+	registry := newJobRegistry(cfg, lock.NewLocker(rdb, owner), sellerService, buyerService, broadcastService)
 
-	// Schedule the job to run every 6 hours
-	c.AddFunc("@every 6h", func() {
-		log.Info(ctx, "Starting ONDC seller lookup cron job...")
-		_, err := sellerService.SyncRegistry(sellerPorts.SellerRegistrySyncRequest{
-			Domains: cfg.Domains,
-		})
+	// If -run-now is provided, run the selected jobs once and exit
+	if runNow.enabled() {
+		jobs, err := runNow.selected(registry)
 		if err != nil {
-			log.Error(ctx, err, "ONDC seller lookup cron job failed")
-		} else {
-			log.Info(ctx, "ONDC seller lookup cron job completed successfully.")
+			log.Fatal(ctx, err, "Invalid -run-now value")
 		}
+		// Exit non-zero if any job failed so schedulers and scripts can tell.
+		failed := 0
+		for _, job := range jobs {
+			if err := job.Execute(ctx); err != nil && !errors.Is(err, ErrJobLocked) {
+				failed++
+			}
+		}
+		if failed > 0 {
+			log.Errorf(ctx, nil, "%d of %d jobs failed", failed, len(jobs))
+			redisClient.Close()
+			os.Exit(1)
+		}
+		return
+	}
 
-	})
+	c := cron.New()
+	if err := registry.Schedule(ctx, c); err != nil {
+		log.Fatal(ctx, err, "Failed to schedule cron jobs")
+	}
 
 	log.Info(ctx, "Starting cron scheduler...")
 	c.Start()
@@ -82,3 +125,44 @@ func main() {
 	// Keep the application running
 	select {}
 }
+
+// newJobRegistry registers the jobs run by this process with their configured schedules
+func newJobRegistry(cfg *config.Config, locker *lock.Locker, sellerService *sellerDomain.SellerService, buyerService *buyerDomain.BuyerService, broadcastService *broadcastDomain.BroadcastService) *Registry {
+	registry := NewRegistry(locker, cfg.CronLockTTL)
+	registry.Register(&Job{
+		Name:        "registry-sync",
+		Description: "Sync sellers from the ONDC registry",
+		Spec:        cfg.CronRegistrySyncSpec,
+		Run: func(ctx context.Context) error {
+			response, err := sellerService.SyncRegistry(ctx, sellerPorts.SellerRegistrySyncRequest{
+				Domains: cfg.Domains,
+			}, sellerPorts.RegistrySyncTriggerCron)
+			if err != nil {
+				return err
+			}
+			if response.Status != sellerPorts.SyncStatusOK {
+				return fmt.Errorf("registry sync finished with status %s", response.Status)
+			}
+			return nil
+		},
+	})
+	registry.Register(&Job{
+		Name:        "policy-refresh",
+		Description: "Re-probe sellers whose policies for active BAPs are about to expire",
+		Spec:        cfg.CronPolicyRefreshSpec,
+		Run: func(ctx context.Context) error {
+			_, err := buyerService.RefreshExpiringPolicies(ctx)
+			return err
+		},
+	})
+	registry.Register(&Job{
+		Name:        "cleanup",
+		Description: "Delete broadcast jobs finished longer than JOB_RETENTION ago",
+		Spec:        cfg.CronCleanupSpec,
+		Run: func(ctx context.Context) error {
+			_, err := broadcastService.CleanupFinishedJobs(ctx)
+			return err
+		},
+	})
+	return registry
+}
@@ -40,9 +40,10 @@ func main() {
 	})
 
 	// Register routes
-	container.SellerHandler.RegisterRoutes(app)
-	container.BuyerHandler.RegisterRoutes(app)
-	container.BroadcastHandler.RegisterRoutes(app)
+	container.SellerHandler.RegisterRoutes(app, container.Auth)
+	container.BuyerHandler.RegisterRoutes(app, container.Auth)
+	container.BroadcastHandler.RegisterRoutes(app, container.Auth)
+	container.LockHandler.RegisterRoutes(app, container.Auth)
 
 	port := container.Config.Port
 
@@ -59,6 +60,13 @@ func main() {
 
 	logger.Infof(ctx, "Starting %s server on port %s", container.Config.OtelService, port)
 
+	workerCtx, stopWorkers := context.WithCancel(ctx)
+	workersDone := make(chan struct{})
+	go func() {
+		defer close(workersDone)
+		container.BroadcastService.RunWorkers(workerCtx)
+	}()
+
 	go func() {
 		if err := app.Listen(":" + port); err != nil {
 			logger.Fatal(ctx, err, "Error starting server")
@@ -71,16 +79,28 @@ func main() {
 	<-c
 	logger.Info(ctx, "Shutting down server...")
 
+	// Unfinished jobs keep their pending targets and are resumed once their lease lapses.
+	stopWorkers()
+
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	if err := container.Shutdown(shutdownCtx); err != nil {
-		logger.Error(ctx, err, "Error during container shutdown")
+	// Workers still write target outcomes and deliver webhooks after being stopped, so the
+	// database and Redis are only closed once they have returned.
+	select {
+	case <-workersDone:
+	case <-shutdownCtx.Done():
+		logger.Warn(ctx, "Broadcast workers did not stop before the shutdown timeout")
 	}
 
+	// Shutting the server down also ends open event streams, which read from Redis.
 	if err := app.ShutdownWithContext(shutdownCtx); err != nil {
 		logger.Error(ctx, err, "Server forced to shutdown")
 	} else {
 		logger.Info(ctx, "Server shutdown complete")
 	}
+
+	if err := container.Shutdown(shutdownCtx); err != nil {
+		logger.Error(ctx, err, "Error during container shutdown")
+	}
 }
@@ -2,25 +2,103 @@ package config
 
 import (
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
 )
 
 type Config struct {
-	DatabaseURL        string   `envconfig:"DATABASE_URL" required:"true"`
-	RedisURL           string   `envconfig:"REDIS_URL" required:"true"`
-	Port               string   `envconfig:"PORT" default:"8080"`
-	LogLevel           string   `envconfig:"LOG_LEVEL" default:"info"`
-	APIKeyHeader       string   `envconfig:"API_KEY_HEADER" default:"X-API-Key"`
-	OtelURL            string   `envconfig:"OTEL_URL"`
-	OtelService        string   `envconfig:"OTEL_SERVICE_NAME" default:"gcr-policy-agent-backend"`
-	Domains            []string `envconfig:"DOMAINS" default:"ONDC:RET10,ONDC:RET11,ONDC:RET12,ONDC:RET13,ONDC:RET14,ONDC:RET15,ONDC:RET16,ONDC:RET17,ONDC:RET18"`
-	RegistryURL        string   `envconfig:"REGISTRY_URL" default:"https://preprod.registry.ondc.org/v2.0/lookup"`
-	PrivateKey         string   `envconfig:"PRIVATE_KEY" default:""`
-	SubscriberID       string   `envconfig:"SUBSCRIBER_ID" default:""`
-	UniqueKeyID        string   `envconfig:"UNIQUE_KEY_ID" default:""`
-	MockSellerResponse bool     `envconfig:"MOCK_SELLER_RESPONSE" default:"false"`
+	DatabaseURL  string   `envconfig:"DATABASE_URL" required:"true"`
+	RedisURL     string   `envconfig:"REDIS_URL" required:"true"`
+	Port         string   `envconfig:"PORT" default:"8080"`
+	LogLevel     string   `envconfig:"LOG_LEVEL" default:"info"`
+	APIKeyHeader string   `envconfig:"API_KEY_HEADER" default:"X-API-Key"`
+	OtelURL      string   `envconfig:"OTEL_URL"`
+	OtelService  string   `envconfig:"OTEL_SERVICE_NAME" default:"gcr-policy-agent-backend"`
+	Domains      []string `envconfig:"DOMAINS" default:"ONDC:RET10,ONDC:RET11,ONDC:RET12,ONDC:RET13,ONDC:RET14,ONDC:RET15,ONDC:RET16,ONDC:RET17,ONDC:RET18"`
+	RegistryURL  string   `envconfig:"REGISTRY_URL" default:"https://preprod.registry.ondc.org/v2.0/lookup"`
+
+	RegistryLookupVersion   string   `envconfig:"REGISTRY_LOOKUP_VERSION" default:"v2"`
+	RegistryCountries       []string `envconfig:"REGISTRY_COUNTRIES" default:"IND"`
+	RegistryCity            string   `envconfig:"REGISTRY_CITY" default:""`
+	RegistrySubscriberTypes []string `envconfig:"REGISTRY_SUBSCRIBER_TYPES" default:"BPP"`
+	RegistryPageSize        int      `envconfig:"REGISTRY_PAGE_SIZE" default:"0"`
+	RegistryMaxPages        int      `envconfig:"REGISTRY_MAX_PAGES" default:"100"`
+	BapRegistryMode         string   `envconfig:"BAP_REGISTRY_MODE" default:"off"`
+	// BapRegistryMaxAge is how long a BAP stays registered after a registry sync last listed it.
+	BapRegistryMaxAge time.Duration `envconfig:"BAP_REGISTRY_MAX_AGE" default:"24h"`
+
+	PrivateKey         string `envconfig:"PRIVATE_KEY" default:""`
+	SubscriberID       string `envconfig:"SUBSCRIBER_ID" default:""`
+	UniqueKeyID        string `envconfig:"UNIQUE_KEY_ID" default:""`
+	SubscriberURL      string `envconfig:"SUBSCRIBER_URL" default:""`
+	MockSellerResponse bool   `envconfig:"MOCK_SELLER_RESPONSE" default:"false"`
+
+	PublicAuthScheme   string        `envconfig:"PUBLIC_AUTH_SCHEME" default:"signature"`
+	InternalAuthScheme string        `envconfig:"INTERNAL_AUTH_SCHEME" default:"api_key"`
+	InternalAPIKey     string        `envconfig:"INTERNAL_API_KEY" default:""`
+	SignatureClockSkew time.Duration `envconfig:"SIGNATURE_CLOCK_SKEW" default:"5s"`
+	SigningKeyCacheTTL time.Duration `envconfig:"SIGNING_KEY_CACHE_TTL" default:"1h"`
+	// SigningKeyMissCacheTTL is how long a subscriber/ukId with no resolvable key is remembered,
+	// so that requests with made-up keyIds do not each cost a registry lookup.
+	SigningKeyMissCacheTTL time.Duration `envconfig:"SIGNING_KEY_MISS_CACHE_TTL" default:"1m"`
+
+	RequestSignatureTTL time.Duration `envconfig:"REQUEST_SIGNATURE_TTL" default:"30s"`
+	SignAsGateway       bool          `envconfig:"SIGN_AS_GATEWAY" default:"false"`
+
+	BroadcastConcurrency   int     `envconfig:"BROADCAST_CONCURRENCY" default:"20"`
+	BroadcastMaxInFlight   int     `envconfig:"BROADCAST_MAX_IN_FLIGHT" default:"100"`
+	BroadcastHostRateLimit float64 `envconfig:"BROADCAST_HOST_RATE_LIMIT" default:"5"`
+	BroadcastHostBurst     int     `envconfig:"BROADCAST_HOST_BURST" default:"5"`
+
+	// BroadcastSellerStatuses lists the registry statuses that may receive /search; "*" allows any.
+	BroadcastSellerStatuses  []string `envconfig:"BROADCAST_SELLER_STATUSES" default:"SUBSCRIBED"`
+	BroadcastEnforceValidity bool     `envconfig:"BROADCAST_ENFORCE_VALIDITY" default:"true"`
+
+	WorkerID              string        `envconfig:"WORKER_ID" default:""`
+	BroadcastWorkers      int           `envconfig:"BROADCAST_WORKERS" default:"2"`
+	BroadcastPollInterval time.Duration `envconfig:"BROADCAST_POLL_INTERVAL" default:"2s"`
+	BroadcastJobLease     time.Duration `envconfig:"BROADCAST_JOB_LEASE" default:"1m"`
+	BroadcastJobTimeout   time.Duration `envconfig:"BROADCAST_JOB_TIMEOUT" default:"5m"`
+	// EventStreamTokenTTL is how long a token issued for a job's event stream can open it.
+	EventStreamTokenTTL time.Duration `envconfig:"EVENT_STREAM_TOKEN_TTL" default:"5m"`
+
+	BroadcastMaxAttempts    int           `envconfig:"BROADCAST_MAX_ATTEMPTS" default:"3"`
+	BroadcastRetryBaseDelay time.Duration `envconfig:"BROADCAST_RETRY_BASE_DELAY" default:"500ms"`
+	BroadcastRetryMaxDelay  time.Duration `envconfig:"BROADCAST_RETRY_MAX_DELAY" default:"10s"`
+
+	WebhookMaxAttempts    int           `envconfig:"WEBHOOK_MAX_ATTEMPTS" default:"5"`
+	WebhookRetryBaseDelay time.Duration `envconfig:"WEBHOOK_RETRY_BASE_DELAY" default:"1s"`
+	WebhookRetryMaxDelay  time.Duration `envconfig:"WEBHOOK_RETRY_MAX_DELAY" default:"30s"`
+	// WebhookAllowedHosts limits callback_url to these hosts and their subdomains; empty allows
+	// any public host. Private, loopback and link-local addresses are refused unless
+	// WebhookAllowPrivateNetworks is set.
+	WebhookAllowedHosts         []string `envconfig:"WEBHOOK_ALLOWED_HOSTS"`
+	WebhookAllowPrivateNetworks bool     `envconfig:"WEBHOOK_ALLOW_PRIVATE_NETWORKS" default:"false"`
+
+	PolicyTTLAllowed       time.Duration `envconfig:"POLICY_TTL_ALLOWED" default:"24h"`
+	PolicyTTLDenied        time.Duration `envconfig:"POLICY_TTL_DENIED" default:"6h"`
+	PolicyTTLErrorOccurred time.Duration `envconfig:"POLICY_TTL_ERROR_OCCURRED" default:"15m"`
+	PolicyTTLPending       time.Duration `envconfig:"POLICY_TTL_PENDING" default:"1h"`
+	PolicyTTLMax           time.Duration `envconfig:"POLICY_TTL_MAX" default:"168h"`
+
+	PolicyRefreshWindow       time.Duration `envconfig:"POLICY_REFRESH_WINDOW" default:"1h"`
+	PolicyRefreshActiveWithin time.Duration `envconfig:"POLICY_REFRESH_ACTIVE_WITHIN" default:"72h"`
+	PolicyRefreshMaxProbes    int           `envconfig:"POLICY_REFRESH_MAX_PROBES" default:"500"`
+	PolicyRefreshMaxPerSeller int           `envconfig:"POLICY_REFRESH_MAX_PER_SELLER" default:"20"`
+	// Policies that expired longer than POLICY_REFRESH_LOOKBACK ago are left to on-demand refresh,
+	// and a policy is not probed again within POLICY_REFRESH_RETRY_AFTER of its last probe.
+	PolicyRefreshLookback   time.Duration `envconfig:"POLICY_REFRESH_LOOKBACK" default:"24h"`
+	PolicyRefreshRetryAfter time.Duration `envconfig:"POLICY_REFRESH_RETRY_AFTER" default:"2h"`
+
+	CronRegistrySyncSpec  string        `envconfig:"CRON_REGISTRY_SYNC_SPEC" default:"@every 6h"`
+	CronPolicyRefreshSpec string        `envconfig:"CRON_POLICY_REFRESH_SPEC" default:"@every 30m"`
+	CronCleanupSpec       string        `envconfig:"CRON_CLEANUP_SPEC" default:"@daily"`
+	CronLockTTL           time.Duration `envconfig:"CRON_LOCK_TTL" default:"1m"`
+	JobRetention          time.Duration `envconfig:"JOB_RETENTION" default:"720h"`
 }
 
 func LoadConfig() (*Config, error) {
@@ -36,5 +114,76 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("error processing envconfig: %w", err)
 	}
 
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
 	return config, nil
 }
+
+// Validate rejects settings that would otherwise only fail, or silently misbehave, at runtime
+func (c *Config) Validate() error {
+	// The workers tick at these intervals; time.NewTicker panics on anything below 1ns.
+	if c.BroadcastPollInterval <= 0 {
+		return fmt.Errorf("BROADCAST_POLL_INTERVAL must be positive, got %s", c.BroadcastPollInterval)
+	}
+	if c.BroadcastJobLease < minBroadcastJobLease {
+		return fmt.Errorf("BROADCAST_JOB_LEASE must be at least %s, got %s", minBroadcastJobLease, c.BroadcastJobLease)
+	}
+	if c.EventStreamTokenTTL <= 0 {
+		return fmt.Errorf("EVENT_STREAM_TOKEN_TTL must be positive, got %s", c.EventStreamTokenTTL)
+	}
+
+	// Refresh probes carry SUBSCRIBER_URL as their bap_uri; without a reachable one every
+	// on_search answer would be lost.
+	if c.SubscriberURL != "" {
+		parsed, err := url.Parse(c.SubscriberURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("SUBSCRIBER_URL must be an absolute http(s) URL, got %q", c.SubscriberURL)
+		}
+	}
+	switch c.BapRegistryMode {
+	case "off":
+	case "report", "strict":
+		// Without BAP syncs no BAP is ever known to the registry, so every one would be flagged.
+		if !containsFold(c.RegistrySubscriberTypes, "BAP") {
+			return fmt.Errorf("BAP_REGISTRY_MODE=%s requires BAP in REGISTRY_SUBSCRIBER_TYPES", c.BapRegistryMode)
+		}
+	default:
+		return fmt.Errorf("BAP_REGISTRY_MODE must be off, report or strict, got %q", c.BapRegistryMode)
+	}
+	return nil
+}
+
+// ValidateServer checks the settings only the API server uses, so that the cron process does not
+// need them.
+func (c *Config) ValidateServer() error {
+	// /v1/internal and the catalog-sync routes must not be left open by an unset key.
+	if c.InternalAuthScheme == "api_key" && c.InternalAPIKey == "" {
+		return fmt.Errorf("INTERNAL_AUTH_SCHEME=api_key requires INTERNAL_API_KEY; set it or choose another scheme explicitly")
+	}
+	return nil
+}
+
+// ValidateCron checks the settings only the cron process uses, so that the API server does not
+// need them.
+func (c *Config) ValidateCron() error {
+	// The scheduled policy refresh could not queue a single probe without SUBSCRIBER_URL.
+	if c.CronPolicyRefreshSpec != "" && c.SubscriberURL == "" {
+		return fmt.Errorf("CRON_POLICY_REFRESH_SPEC requires SUBSCRIBER_URL; set it or disable the refresh with an empty spec")
+	}
+	return nil
+}
+
+// minBroadcastJobLease keeps the lease heartbeat, which renews every third of the lease, well
+// above database round-trip times.
+const minBroadcastJobLease = 3 * time.Second
+
+func containsFold(values []string, want string) bool {
+	for _, value := range values {
+		if strings.EqualFold(strings.TrimSpace(value), want) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+func defaultConfig(t *testing.T) *Config {
+	t.Helper()
+	t.Setenv("DATABASE_URL", "unused")
+	t.Setenv("REDIS_URL", "unused")
+	t.Setenv("SUBSCRIBER_URL", "https://adapter.example/ondc")
+	cfg := &Config{}
+	if err := envconfig.Process("", cfg); err != nil {
+		t.Fatalf("load default config: %v", err)
+	}
+	return cfg
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(*Config)
+		wantErr string
+	}{
+		{name: "defaults"},
+		{
+			name:    "zero poll interval",
+			edit:    func(c *Config) { c.BroadcastPollInterval = 0 },
+			wantErr: "BROADCAST_POLL_INTERVAL must be positive",
+		},
+		{
+			name:    "lease too short to heartbeat",
+			edit:    func(c *Config) { c.BroadcastJobLease = 2 },
+			wantErr: "BROADCAST_JOB_LEASE must be at least",
+		},
+		{
+			// Only the cron process needs it, see TestValidateCron.
+			name: "no subscriber url",
+			edit: func(c *Config) { c.SubscriberURL = "" },
+		},
+		{
+			name:    "relative subscriber url",
+			edit:    func(c *Config) { c.SubscriberURL = "adapter.example/ondc" },
+			wantErr: "SUBSCRIBER_URL must be an absolute http(s) URL",
+		},
+		{
+			name:    "unknown registry mode",
+			edit:    func(c *Config) { c.BapRegistryMode = "enforce" },
+			wantErr: "BAP_REGISTRY_MODE must be",
+		},
+		{
+			name:    "strict mode without BAP sync",
+			edit:    func(c *Config) { c.BapRegistryMode = "strict" },
+			wantErr: "requires BAP in REGISTRY_SUBSCRIBER_TYPES",
+		},
+		{
+			name: "strict mode with BAP sync",
+			edit: func(c *Config) {
+				c.BapRegistryMode = "strict"
+				c.RegistrySubscriberTypes = []string{"BPP", "bap"}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaultConfig(t)
+			if tt.edit != nil {
+				tt.edit(cfg)
+			}
+			err := cfg.Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Validate() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateServer(t *testing.T) {
+	cfg := defaultConfig(t)
+	if cfg.InternalAuthScheme != "api_key" {
+		t.Errorf("INTERNAL_AUTH_SCHEME defaults to %q, want api_key", cfg.InternalAuthScheme)
+	}
+	if err := cfg.ValidateServer(); err == nil || !strings.Contains(err.Error(), "requires INTERNAL_API_KEY") {
+		t.Errorf("ValidateServer() without a key = %v, want an error", err)
+	}
+
+	cfg.InternalAPIKey = "secret"
+	if err := cfg.ValidateServer(); err != nil {
+		t.Errorf("ValidateServer() with a key = %v, want nil", err)
+	}
+
+	cfg.InternalAPIKey = ""
+	cfg.InternalAuthScheme = "none"
+	if err := cfg.ValidateServer(); err != nil {
+		t.Errorf("ValidateServer() with internal auth explicitly off = %v, want nil", err)
+	}
+}
+
+func TestValidateCron(t *testing.T) {
+	cfg := defaultConfig(t)
+	if err := cfg.ValidateCron(); err != nil {
+		t.Errorf("ValidateCron() with defaults = %v, want nil", err)
+	}
+
+	cfg.SubscriberURL = ""
+	if err := cfg.ValidateCron(); err == nil || !strings.Contains(err.Error(), "CRON_POLICY_REFRESH_SPEC requires SUBSCRIBER_URL") {
+		t.Errorf("ValidateCron() for policy refresh without subscriber url = %v, want an error", err)
+	}
+
+	cfg.CronPolicyRefreshSpec = ""
+	if err := cfg.ValidateCron(); err != nil {
+		t.Errorf("ValidateCron() with policy refresh disabled = %v, want nil", err)
+	}
+}
@@ -0,0 +1,26 @@
+package di
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// backfillStatements rewrite rows stored by older releases into the current format. Each one is
+// idempotent, so they run on every start after AutoMigrate.
+var backfillStatements = []string{
+	// Validity used to be stored as the zero time when the registry sent none; it is NULL now.
+	`UPDATE sellers SET valid_from = NULL WHERE valid_from < '0001-01-02'`,
+	`UPDATE sellers SET valid_until = NULL WHERE valid_until < '0001-01-02'`,
+	`UPDATE baps SET valid_from = NULL WHERE valid_from < '0001-01-02'`,
+	`UPDATE baps SET valid_until = NULL WHERE valid_until < '0001-01-02'`,
+}
+
+func backfillData(db *gorm.DB) error {
+	for _, statement := range backfillStatements {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("backfill %q: %w", statement, err)
+		}
+	}
+	return nil
+}
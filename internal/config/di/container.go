@@ -12,13 +12,16 @@ import (
 	sellerDomain "adapter/internal/domain/seller"
 	broadcastHandler "adapter/internal/handlers/broadcast"
 	buyerHandler "adapter/internal/handlers/buyer"
+	lockHandler "adapter/internal/handlers/locks"
 	sellerHandler "adapter/internal/handlers/seller"
 	buyerPorts "adapter/internal/ports/buyer"
 	sellerPorts "adapter/internal/ports/seller"
 	"adapter/internal/shared/caching"
 	db "adapter/internal/shared/database"
+	"adapter/internal/shared/lock"
 	logger "adapter/internal/shared/log"
-	// redisClient "adapter/internal/shared/redis"
+	"adapter/internal/shared/middleware"
+	redisClient "adapter/internal/shared/redis"
 )
 
 type Container struct {
@@ -28,6 +31,9 @@ type Container struct {
 	SellerHandler    *sellerHandler.SellerHandler
 	BuyerHandler     *buyerHandler.BuyerHandler
 	BroadcastHandler *broadcastHandler.BroadcastHandler
+	LockHandler      *lockHandler.LockHandler
+	BroadcastService *broadcastDomain.BroadcastService
+	Auth             middleware.RouteAuth
 }
 
 func (c *Container) Shutdown(ctx context.Context) error {
@@ -39,6 +45,10 @@ func (c *Container) Shutdown(ctx context.Context) error {
 		}
 	}
 
+	if err := redisClient.Close(); err != nil {
+		logger.Error(ctx, err, "Failed to close redis connection")
+	}
+
 	logger.Info(ctx, "Container shutdown complete")
 	return nil
 }
@@ -49,6 +59,9 @@ func InitContainer() (*Container, error) {
 	if err != nil {
 		logger.Fatal(ctx, fmt.Errorf("failed to load config: %w", err), "Configuration error")
 	}
+	if err := cfg.ValidateServer(); err != nil {
+		logger.Fatal(ctx, fmt.Errorf("invalid configuration: %w", err), "Configuration error")
+	}
 
 	database, err := db.Init(cfg.DatabaseURL)
 	if err != nil {
@@ -57,28 +70,68 @@ func InitContainer() (*Container, error) {
 	}
 
 	logger.Info(ctx, "Running database migrations...")
-	if err := database.AutoMigrate(&sellerPorts.Seller{}, &buyerPorts.Bap{}, &sellerPorts.SellerCatalogState{}, &buyerPorts.BapAccessPolicy{}, &buyerPorts.PermissionsJob{}); err != nil {
+	if err := database.AutoMigrate(&sellerPorts.Seller{}, &buyerPorts.Bap{}, &sellerPorts.SellerCatalogState{}, &buyerPorts.BapAccessPolicy{}, &buyerPorts.PermissionsJob{}, &buyerPorts.OnSearchResponse{}, &buyerPorts.PermissionsJobTarget{}, &buyerPorts.WebhookDelivery{}, &sellerPorts.RegistrySyncRun{}, &sellerPorts.RegistrySyncRunDomain{}, &sellerPorts.SellerChange{}); err != nil {
 		logger.Fatal(ctx, err, "Failed to run database migrations")
 		return nil, fmt.Errorf("failed to run database migrations: %w", err)
 	}
+	if err := backfillData(database); err != nil {
+		logger.Fatal(ctx, err, "Failed to backfill data")
+		return nil, fmt.Errorf("failed to backfill data: %w", err)
+	}
 	logger.Info(ctx, "Database migrations completed successfully")
 
+	rdb, err := redisClient.Init(cfg.RedisURL)
+	if err != nil {
+		logger.Fatal(ctx, fmt.Errorf("failed to initialize redis: %w", err), "Redis initialization error")
+		return nil, fmt.Errorf("failed to initialize redis: %w", err)
+	}
+	cacheService := caching.NewRedisCacheService(rdb)
+
 	sellerRepo := sellerPorts.NewSellerRepository(database)
-	sellerService := sellerDomain.NewSellerService(sellerRepo, cfg)
+	buyerRepo := buyerPorts.NewBuyerRepository(database)
+	sellerService := sellerDomain.NewSellerService(sellerRepo, buyerRepo, cfg)
 	sellerHandler := sellerHandler.NewSellerHandler(sellerService)
 
-	buyerRepo := buyerPorts.NewBuyerRepository(database)
-	buyerService := buyerDomain.NewBuyerService(buyerRepo)
-	buyerHandler := buyerHandler.NewBuyerHandler(buyerService)
+	keyResolver := sellerDomain.NewSigningKeyResolver(sellerService, cacheService, cfg.SigningKeyCacheTTL, cfg.SigningKeyMissCacheTTL)
+	publicAuth, err := middleware.NewAuthMiddleware(middleware.AuthConfig{
+		Scheme:    cfg.PublicAuthScheme,
+		Realm:     cfg.SubscriberID,
+		ClockSkew: cfg.SignatureClockSkew,
+		Resolver:  keyResolver,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure public route auth: %w", err)
+	}
+	internalAuth, err := middleware.NewAuthMiddleware(middleware.AuthConfig{
+		Scheme:    cfg.InternalAuthScheme,
+		Header:    cfg.APIKeyHeader,
+		APIKey:    cfg.InternalAPIKey,
+		Realm:     cfg.SubscriberID,
+		ClockSkew: cfg.SignatureClockSkew,
+		Resolver:  keyResolver,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure internal route auth: %w", err)
+	}
 
-	broadcastService := broadcastDomain.NewBroadcastService(buyerRepo, sellerRepo, cfg)
+	broadcastService := broadcastDomain.NewBroadcastService(buyerRepo, sellerRepo, redisClient.NewPubSub(rdb), cacheService, cfg)
 	broadcastHandler := broadcastHandler.NewBroadcastHandler(broadcastService)
 
+	// The API only reads lock holders; the locks themselves are taken by cron replicas.
+	lockHandler := lockHandler.NewLockHandler(lock.NewLocker(rdb, cfg.WorkerID))
+
+	buyerService := buyerDomain.NewBuyerService(buyerRepo, broadcastService, cfg)
+	buyerHandler := buyerHandler.NewBuyerHandler(buyerService)
+
 	return &Container{
 		Config:           cfg,
 		DB:               database,
+		CacheService:     cacheService,
 		SellerHandler:    sellerHandler,
 		BuyerHandler:     buyerHandler,
 		BroadcastHandler: broadcastHandler,
-	}, err
+		LockHandler:      lockHandler,
+		BroadcastService: broadcastService,
+		Auth:             middleware.RouteAuth{Public: publicAuth, Internal: internalAuth},
+	}, nil
 }
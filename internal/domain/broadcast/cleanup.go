@@ -0,0 +1,22 @@
+package broadcast
+
+import (
+	"context"
+	"time"
+
+	"adapter/internal/shared/log"
+)
+
+// CleanupFinishedJobs deletes broadcast jobs, and everything recorded for them, that finished
+// longer than JOB_RETENTION ago.
+func (s *BroadcastService) CleanupFinishedJobs(ctx context.Context) (int64, error) {
+	cutoff := time.Now().Add(-s.config.JobRetention)
+
+	deleted, err := s.buyerRepo.DeleteFinishedPermissionsJobs(ctx, cutoff)
+	if err != nil {
+		log.Errorf(ctx, err, "Failed to delete broadcast jobs finished before %s", cutoff.Format(time.RFC3339))
+		return 0, err
+	}
+	log.Infof(ctx, "Deleted %d broadcast jobs finished before %s", deleted, cutoff.Format(time.RFC3339))
+	return deleted, nil
+}
@@ -0,0 +1,43 @@
+package broadcast
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	sellerPorts "adapter/internal/ports/seller"
+)
+
+// anySellerStatus in BROADCAST_SELLER_STATUSES lets sellers in any registry status be contacted
+const anySellerStatus = "*"
+
+// targetSkipReason explains why a seller must not be sent /search, or returns "" when it may be.
+// Validity bounds the registry left empty are not enforced.
+func (s *BroadcastService) targetSkipReason(sel sellerPorts.Seller, now time.Time) string {
+	if !s.sellerStatusAllowed(sel.Status) {
+		return fmt.Sprintf("registry status %q is not eligible for broadcast", sel.Status)
+	}
+	if !s.config.BroadcastEnforceValidity {
+		return ""
+	}
+	if sel.ValidFrom != nil && !sel.ValidFrom.IsZero() && now.Before(*sel.ValidFrom) {
+		return fmt.Sprintf("subscription not valid until %s", sel.ValidFrom.Format(time.RFC3339))
+	}
+	if sel.ValidUntil != nil && !sel.ValidUntil.IsZero() && now.After(*sel.ValidUntil) {
+		return fmt.Sprintf("subscription expired at %s", sel.ValidUntil.Format(time.RFC3339))
+	}
+	return ""
+}
+
+func (s *BroadcastService) sellerStatusAllowed(status string) bool {
+	if len(s.config.BroadcastSellerStatuses) == 0 {
+		return true
+	}
+	for _, allowed := range s.config.BroadcastSellerStatuses {
+		allowed = strings.TrimSpace(allowed)
+		if allowed == anySellerStatus || strings.EqualFold(allowed, status) {
+			return true
+		}
+	}
+	return false
+}
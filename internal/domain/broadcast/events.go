@@ -0,0 +1,223 @@
+package broadcast
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"adapter/internal/ports/broadcast"
+	"adapter/internal/ports/buyer"
+	"adapter/internal/shared/log"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+// EventBus carries broadcast progress between replicas: the worker running a job publishes
+// and the replica serving an event stream subscribes.
+type EventBus interface {
+	Publish(ctx context.Context, channel string, payload []byte) error
+	Subscribe(ctx context.Context, channel string) (<-chan []byte, func() error, error)
+}
+
+const (
+	eventReplayPageSize = 500
+	// eventStatusPollInterval bounds how long a stream stays open if the final event was missed.
+	eventStatusPollInterval = 10 * time.Second
+)
+
+func eventChannel(jobID uuid.UUID) string {
+	return fmt.Sprintf("broadcast:job:%s:events", jobID)
+}
+
+// ErrInvalidEventStreamToken is returned for a stream token that is unknown, expired or was
+// issued for another job.
+var ErrInvalidEventStreamToken = errors.New("invalid or expired event stream token")
+
+// eventStreamGrant is what a stream token stands for.
+type eventStreamGrant struct {
+	JobID uuid.UUID `json:"job_id"`
+	BapID string    `json:"bap_id"`
+}
+
+func eventStreamTokenKey(token string) string {
+	return "broadcast:stream_token:" + token
+}
+
+// IssueEventStreamToken returns a token that opens the job's event stream on behalf of bapID
+// until it expires. Browsers' EventSource cannot sign its request, so it passes the token as a
+// query parameter instead. The token may be reused until then, which lets EventSource reconnect.
+func (s *BroadcastService) IssueEventStreamToken(ctx context.Context, jobID uuid.UUID, bapID string) (*broadcast.EventStreamTokenResponse, error) {
+	if _, err := s.getCallerJob(jobID, bapID); err != nil {
+		return nil, err
+	}
+	if s.cache == nil {
+		return nil, errors.New("event stream tokens need a cache")
+	}
+
+	token := uuid.NewString()
+	ttl := s.config.EventStreamTokenTTL
+	if err := s.cache.Set(ctx, eventStreamTokenKey(token), eventStreamGrant{JobID: jobID, BapID: bapID}, ttl); err != nil {
+		log.Errorf(ctx, err, "Failed to store event stream token for job %s", jobID)
+		return nil, err
+	}
+	return &broadcast.EventStreamTokenResponse{Token: token, ExpiresAt: time.Now().Add(ttl)}, nil
+}
+
+// RedeemEventStreamToken returns the BAP a stream token was issued to for jobID.
+func (s *BroadcastService) RedeemEventStreamToken(ctx context.Context, jobID uuid.UUID, token string) (string, error) {
+	if s.cache == nil {
+		return "", ErrInvalidEventStreamToken
+	}
+	var grant eventStreamGrant
+	if err := s.cache.Get(ctx, eventStreamTokenKey(token), &grant); err != nil {
+		if errors.Is(err, redis.Nil) {
+			return "", ErrInvalidEventStreamToken
+		}
+		log.Errorf(ctx, err, "Failed to look up event stream token for job %s", jobID)
+		return "", err
+	}
+	if grant.JobID != jobID {
+		return "", ErrInvalidEventStreamToken
+	}
+	return grant.BapID, nil
+}
+
+func (s *BroadcastService) publishEvent(jobID uuid.UUID, event broadcast.BroadcastEvent) {
+	if s.events == nil {
+		return
+	}
+	ctx := context.Background()
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Errorf(ctx, err, "Failed to serialize %s event for job %s", event.Type, jobID)
+		return
+	}
+	if err := s.events.Publish(ctx, eventChannel(jobID), payload); err != nil {
+		log.Errorf(ctx, err, "Failed to publish %s event for job %s", event.Type, jobID)
+	}
+}
+
+func (s *BroadcastService) publishTargetEvent(target buyer.PermissionsJobTarget) {
+	s.publishEvent(target.JobID, broadcast.BroadcastEvent{Type: broadcast.EventTypeTarget, Target: &target})
+}
+
+func (s *BroadcastService) publishJobFinished(jobID uuid.UUID) {
+	job, err := s.buyerRepo.GetPermissionsJobByID(jobID)
+	if err != nil {
+		log.Errorf(context.Background(), err, "Failed to load job %s for its job_finished event", jobID)
+		return
+	}
+	s.publishEvent(jobID, broadcast.BroadcastEvent{Type: broadcast.EventTypeJobFinished, Job: job})
+}
+
+// SubscribeBroadcastEvents streams the progress of a job. Targets that already have an outcome
+// are replayed first, then live outcomes follow until the job finishes or ctx is cancelled.
+// The returned channel is closed after the job_finished event. Only the BAP that started the
+// job may subscribe to it.
+func (s *BroadcastService) SubscribeBroadcastEvents(ctx context.Context, jobID uuid.UUID, bapID string) (<-chan broadcast.BroadcastEvent, error) {
+	if _, err := s.getCallerJob(jobID, bapID); err != nil {
+		return nil, err
+	}
+
+	// Subscribe before replaying so that nothing finishing in between is lost.
+	var live <-chan []byte
+	closeSub := func() error { return nil }
+	if s.events != nil {
+		var err error
+		live, closeSub, err = s.events.Subscribe(ctx, eventChannel(jobID))
+		if err != nil {
+			log.Errorf(ctx, err, "Failed to subscribe to events of job %s", jobID)
+			return nil, err
+		}
+	}
+
+	out := make(chan broadcast.BroadcastEvent)
+	go func() {
+		defer close(out)
+		defer closeSub()
+
+		send := func(event broadcast.BroadcastEvent) bool {
+			select {
+			case out <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		replayed := make(map[string]buyer.TargetOutcome)
+		for offset := 0; ; offset += eventReplayPageSize {
+			targets, err := s.buyerRepo.ListPermissionsJobTargets(jobID, "", eventReplayPageSize, offset)
+			if err != nil {
+				log.Errorf(ctx, err, "Failed to replay targets of job %s", jobID)
+				return
+			}
+			hasMore := len(targets) > eventReplayPageSize
+			if hasMore {
+				targets = targets[:eventReplayPageSize]
+			}
+			for i := range targets {
+				if targets[i].Outcome == buyer.TargetOutcomePending {
+					continue
+				}
+				replayed[targets[i].SellerID] = targets[i].Outcome
+				if !send(broadcast.BroadcastEvent{Type: broadcast.EventTypeTarget, Target: &targets[i]}) {
+					return
+				}
+			}
+			if !hasMore {
+				break
+			}
+		}
+
+		finished := func() bool {
+			job, err := s.buyerRepo.GetPermissionsJobByID(jobID)
+			if err != nil {
+				log.Errorf(ctx, err, "Failed to check status of job %s", jobID)
+				return false
+			}
+			if job.Status == buyer.JobStatusInitiated || job.Status == buyer.JobStatusRunning {
+				return false
+			}
+			send(broadcast.BroadcastEvent{Type: broadcast.EventTypeJobFinished, Job: job})
+			return true
+		}
+		if finished() {
+			return
+		}
+
+		ticker := time.NewTicker(eventStatusPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if finished() {
+					return
+				}
+			case payload, ok := <-live:
+				if !ok {
+					return
+				}
+				var event broadcast.BroadcastEvent
+				if err := json.Unmarshal(payload, &event); err != nil {
+					log.Errorf(ctx, err, "Discarding malformed event for job %s", jobID)
+					continue
+				}
+				if event.Type == broadcast.EventTypeTarget && event.Target != nil {
+					if outcome, ok := replayed[event.Target.SellerID]; ok && outcome == event.Target.Outcome {
+						continue
+					}
+				}
+				if !send(event) || event.Type == broadcast.EventTypeJobFinished {
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
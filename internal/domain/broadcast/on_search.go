@@ -0,0 +1,174 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"adapter/internal/ports/broadcast"
+	"adapter/internal/ports/buyer"
+	sellerPorts "adapter/internal/ports/seller"
+	"adapter/internal/shared/log"
+
+	"gorm.io/gorm"
+)
+
+// Sellers can state an explicit access decision in their catalog with a tag of the form
+// {"code": "bap_access", "list": [{"code": "decision", "value": "DENIED"}, {"code": "reason", "value": "..."}]}
+const (
+	accessPolicyTagCode = "bap_access"
+	decisionTagCode     = "decision"
+	reasonTagCode       = "reason"
+)
+
+var (
+	ErrUnknownTransaction     = errors.New("no permissions job found for transaction")
+	ErrOnSearchSignerMismatch = errors.New("on_search signer does not match context.bpp_id")
+	ErrNotJobTarget           = errors.New("seller was not sent /search for this permissions job")
+)
+
+// HandleOnSearch correlates an on_search callback with its permissions job, records the
+// seller's outcome and updates the BAP access policy accordingly. signerID is the subscriber
+// that signed the callback, or "" when the callback route is unauthenticated. Only a seller the
+// job actually sent /search to may answer, and the policy is stored for the domain it was asked
+// about rather than the one the callback claims.
+func (s *BroadcastService) HandleOnSearch(req broadcast.OnSearchRequest, signerID string) (*buyer.OnSearchResponse, error) {
+	ctx := context.Background()
+
+	if req.Context == nil || req.Context.TransactionID == "" || req.Context.BppID == "" {
+		return nil, fmt.Errorf("context with transaction_id and bpp_id is required")
+	}
+	if signerID != "" && signerID != req.Context.BppID {
+		log.Warnf(ctx, "Rejecting on_search for bpp_id %s signed by %s", req.Context.BppID, signerID)
+		return nil, ErrOnSearchSignerMismatch
+	}
+
+	job, err := s.buyerRepo.GetPermissionsJobByTransaction(req.Context.TransactionID, req.Context.MessageID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			log.Warnf(ctx, "Received on_search from %s for unknown transaction %s", req.Context.BppID, req.Context.TransactionID)
+			return nil, ErrUnknownTransaction
+		}
+		return nil, err
+	}
+
+	target, err := s.buyerRepo.GetPermissionsJobTarget(job.ID, req.Context.BppID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if err == gorm.ErrRecordNotFound || target.Outcome == buyer.TargetOutcomeSkipped {
+		log.Warnf(ctx, "Rejecting on_search from %s: not a target of job %s", req.Context.BppID, job.ID)
+		return nil, ErrNotJobTarget
+	}
+
+	now := time.Now()
+	record := &buyer.OnSearchResponse{
+		JobID:           job.ID,
+		SellerID:        target.SellerID,
+		Domain:          target.Domain,
+		TransactionID:   req.Context.TransactionID,
+		MessageID:       req.Context.MessageID,
+		CatalogReceived: req.Message != nil && req.Message.Catalog != nil,
+		ReceivedAt:      now,
+	}
+
+	decision, reason := decideFromOnSearch(req)
+	record.Decision = decision
+	if req.Error != nil && req.Error.Code != "" {
+		record.ErrorCode = &req.Error.Code
+		record.ErrorMessage = &req.Error.Message
+	}
+
+	if err := s.buyerRepo.CreateOnSearchResponse(record); err != nil {
+		log.Errorf(ctx, err, "Failed to record on_search response from %s for job %s", record.SellerID, job.ID)
+		return nil, err
+	}
+
+	if decision == "" {
+		log.Infof(ctx, "on_search from %s for job %s carried no catalog, policy or error; leaving policy unchanged", record.SellerID, job.ID)
+		return record, nil
+	}
+
+	var tags []*broadcast.Tag
+	if req.Message != nil && req.Message.Catalog != nil {
+		tags = req.Message.Catalog.Tags
+	}
+	policy := buyer.BapAccessPolicy{
+		SellerID:       record.SellerID,
+		Domain:         record.Domain,
+		BapID:          job.BapID,
+		Decision:       decision,
+		DecisionSource: sellerPorts.SourceSellerOnSearch,
+		DecidedAt:      now,
+		ExpiresAt:      s.policyExpiry(decision, now, sellerPolicyTTL(tags)),
+		Reason:         reason,
+	}
+	stored, err := s.buyerRepo.UpsertBapAccessPolicies([]buyer.BapAccessPolicy{policy})
+	if err != nil {
+		log.Errorf(ctx, err, "Failed to upsert BapAccessPolicy from on_search for seller %s and bap_id %s", record.SellerID, job.BapID)
+		return nil, err
+	}
+	if err := s.buyerRepo.UpdatePermissionsJobTargetDecision(job.ID, record.SellerID, decision); err != nil {
+		log.Errorf(ctx, err, "Failed to update target decision for seller %s in job %s", record.SellerID, job.ID)
+	}
+
+	if !stored[0] {
+		log.Infof(ctx, "Kept the existing BapAccessPolicy for seller %s and bap_id %s over %s from on_search", record.SellerID, job.BapID, decision)
+		return record, nil
+	}
+	log.Infof(ctx, "Updated BapAccessPolicy for seller %s and bap_id %s to %s from on_search", record.SellerID, job.BapID, decision)
+	return record, nil
+}
+
+// decideFromOnSearch maps a callback to an access decision. An error code denies access,
+// an explicit policy tag wins over the catalog, and a catalog on its own allows access.
+func decideFromOnSearch(req broadcast.OnSearchRequest) (sellerPorts.AccessDecision, *string) {
+	if req.Error != nil && req.Error.Code != "" {
+		reason := fmt.Sprintf("on_search error %s: %s", req.Error.Code, req.Error.Message)
+		return sellerPorts.DecisionDenied, &reason
+	}
+
+	if req.Message == nil || req.Message.Catalog == nil {
+		return "", nil
+	}
+
+	if tagDecision, tagReason := accessPolicyFromTags(req.Message.Catalog.Tags); tagDecision != "" {
+		return tagDecision, tagReason
+	}
+
+	reason := "Catalog received in on_search"
+	return sellerPorts.DecisionAllowed, &reason
+}
+
+func accessPolicyFromTags(tags []*broadcast.Tag) (sellerPorts.AccessDecision, *string) {
+	for _, tag := range tags {
+		if tag == nil || tag.Code != accessPolicyTagCode {
+			continue
+		}
+		var decision sellerPorts.AccessDecision
+		var reason *string
+		for _, item := range tag.List {
+			if item == nil {
+				continue
+			}
+			switch item.Code {
+			case decisionTagCode:
+				switch sellerPorts.AccessDecision(strings.ToUpper(item.Value)) {
+				case sellerPorts.DecisionAllowed:
+					decision = sellerPorts.DecisionAllowed
+				case sellerPorts.DecisionDenied:
+					decision = sellerPorts.DecisionDenied
+				}
+			case reasonTagCode:
+				value := item.Value
+				reason = &value
+			}
+		}
+		if decision != "" {
+			return decision, reason
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,62 @@
+package broadcast
+
+import (
+	"fmt"
+	"time"
+
+	"adapter/internal/ports/broadcast"
+	"adapter/internal/ports/buyer"
+
+	"github.com/google/uuid"
+)
+
+const refreshCoreVersion = "1.2.0"
+
+// EnqueueRefresh queues a broadcast that re-asks the given sellers for their decision on a BAP.
+// The /search is sent with this adapter as the bap_uri so that on_search callbacks land here.
+// Sellers already targeted by a queued or running job for the BAP are not probed again; when that
+// covers all of them, the job probing the first seller is returned instead of a new one.
+func (s *BroadcastService) EnqueueRefresh(bapID, domain string, sellerIDs []string) (*buyer.PermissionsJob, error) {
+	if len(sellerIDs) == 0 {
+		return nil, fmt.Errorf("no sellers to refresh")
+	}
+	if s.config.SubscriberURL == "" {
+		return nil, fmt.Errorf("SUBSCRIBER_URL is required to receive refresh on_search callbacks")
+	}
+
+	inFlight, err := s.buyerRepo.GetOpenPermissionsJobSellers(bapID, domain, sellerIDs)
+	if err != nil {
+		return nil, err
+	}
+	remaining := make([]string, 0, len(sellerIDs))
+	for _, sellerID := range sellerIDs {
+		if _, ok := inFlight[sellerID]; !ok {
+			remaining = append(remaining, sellerID)
+		}
+	}
+	if len(remaining) == 0 {
+		return s.buyerRepo.GetPermissionsJobByID(inFlight[sellerIDs[0]])
+	}
+
+	req := broadcast.BroadcastRequest{
+		SearchPayload: &broadcast.SearchPayload{
+			Context: &broadcast.Context{
+				Domain:        domain,
+				Action:        "search",
+				Country:       "IND",
+				City:          "*",
+				CoreVersion:   refreshCoreVersion,
+				BapID:         bapID,
+				BapURI:        s.config.SubscriberURL,
+				TransactionID: uuid.NewString(),
+				MessageID:     uuid.NewString(),
+				Timestamp:     time.Now().UTC().Format(time.RFC3339Nano),
+				TTL:           "PT30S",
+			},
+			Message: &broadcast.Message{Intent: &broadcast.Intent{}},
+		},
+		SellerIDs: remaining,
+	}
+	// Refreshes are started by the adapter itself rather than by a signed request.
+	return s.BroadcastPermissions(req, "")
+}
@@ -0,0 +1,119 @@
+package broadcast
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// shouldRetry reports whether an attempt failed transiently: timeouts, refused or reset
+// connections, 5xx responses and 429s. For 429/503 the server's Retry-After is returned as well.
+// Nothing is retried once ctx is done.
+func shouldRetry(ctx context.Context, resp *resty.Response, err error) (time.Duration, bool) {
+	if ctx.Err() != nil {
+		return 0, false
+	}
+	if err != nil {
+		return 0, isTransientError(err)
+	}
+	if resp == nil {
+		return 0, false
+	}
+
+	status := resp.StatusCode()
+	if status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable {
+		return parseRetryAfter(resp.Header().Get("Retry-After")), true
+	}
+	return 0, status >= 500
+}
+
+// isTransientError reports whether a transport error may go away on its own. Certificate and
+// TLS failures, malformed URLs and anything unrecognised are permanent.
+func isTransientError(err error) bool {
+	var (
+		certInvalid   x509.CertificateInvalidError
+		hostMismatch  x509.HostnameError
+		unknownCA     x509.UnknownAuthorityError
+		verifyFailure *tls.CertificateVerificationError
+		recordHeader  tls.RecordHeaderError
+	)
+	if errors.As(err, &certInvalid) || errors.As(err, &hostMismatch) || errors.As(err, &unknownCA) ||
+		errors.As(err, &verifyFailure) || errors.As(err, &recordHeader) {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+	return isTimeout(err)
+}
+
+// parseRetryAfter accepts both the delay-seconds and HTTP-date forms of Retry-After.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if d := time.Until(at); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// backoff returns the wait before the next /search attempt.
+func (s *BroadcastService) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	return backoffDelay(s.config.BroadcastRetryBaseDelay, s.config.BroadcastRetryMaxDelay, attempt, retryAfter)
+}
+
+// backoffDelay grows exponentially from base with jitter, is never shorter than the
+// server's Retry-After and never longer than maxDelay.
+func backoffDelay(base, maxDelay time.Duration, attempt int, retryAfter time.Duration) time.Duration {
+	delay := base << (attempt - 1)
+	if delay <= 0 || delay > maxDelay {
+		delay = maxDelay
+	}
+	// Equal jitter: keep half the delay and randomise the rest.
+	if half := delay / 2; half > 0 {
+		delay = half + time.Duration(rand.Int63n(int64(half)))
+	}
+
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// sleepContext waits for d, returning false if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
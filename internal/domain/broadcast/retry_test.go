@@ -0,0 +1,63 @@
+package broadcast
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestShouldRetryTransportErrors(t *testing.T) {
+	dial := func(err error) error {
+		return &url.Error{Op: "Post", URL: "https://seller.example/search", Err: &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", err)}}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection refused", dial(syscall.ECONNREFUSED), true},
+		{"connection reset", dial(syscall.ECONNRESET), true},
+		{"client timeout", &url.Error{Op: "Post", URL: "https://seller.example/search", Err: timeoutError{}}, true},
+		{"server closed connection", &url.Error{Op: "Post", URL: "https://seller.example/search", Err: io.EOF}, true},
+		{"temporary dns failure", &net.DNSError{Err: "server misbehaving", Name: "seller.example", IsTemporary: true}, true},
+		{"unknown host", &net.DNSError{Err: "no such host", Name: "seller.example", IsNotFound: true}, false},
+		{"unknown certificate authority", &url.Error{Op: "Post", URL: "https://seller.example/search", Err: x509.UnknownAuthorityError{}}, false},
+		{"certificate for another host", &url.Error{Op: "Post", URL: "https://seller.example/search", Err: x509.HostnameError{Host: "seller.example"}}, false},
+		{"invalid url", &url.Error{Op: "parse", URL: "::", Err: errors.New("missing protocol scheme")}, false},
+		{"unsupported scheme", fmt.Errorf("unsupported protocol scheme %q", "ftp"), false},
+	}
+	for _, tt := range tests {
+		if _, got := shouldRetry(context.Background(), nil, tt.err); got != tt.want {
+			t.Errorf("%s: shouldRetry = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestShouldRetryStopsWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, retry := shouldRetry(ctx, nil, syscall.ECONNREFUSED); retry {
+		t.Error("a cancelled attempt should not be retried")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	if _, retry := shouldRetry(ctx, nil, context.DeadlineExceeded); retry {
+		t.Error("an attempt past its deadline should not be retried")
+	}
+}
@@ -5,16 +5,22 @@ import (
 	"adapter/internal/ports/broadcast"
 	"adapter/internal/ports/buyer"
 	sellerPorts "adapter/internal/ports/seller"
+	"adapter/internal/shared/caching"
+	"adapter/internal/shared/crypto"
 	"adapter/internal/shared/log"
+	"adapter/internal/shared/ratelimit"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/go-resty/resty/v2"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"net"
 	url_pkg "net/url"
 	"path"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -22,19 +28,65 @@ type BroadcastService struct {
 	buyerRepo  buyer.PermissionsRepository
 	sellerRepo sellerPorts.SellerRepository
 	httpClient *resty.Client
-	config     *config.Config
+	// webhookClient only dials public addresses and does not follow redirects.
+	webhookClient *resty.Client
+	signer        *crypto.RequestSigner
+	config        *config.Config
+
+	// inFlight caps concurrent /search requests across all jobs, hostLimiter paces requests per seller host.
+	inFlight    *ratelimit.Semaphore
+	hostLimiter *ratelimit.HostLimiter
+
+	// events publishes per-target progress for event stream subscribers on any replica.
+	events EventBus
+	// cache holds the tokens that open event streams without a signature.
+	cache caching.CacheService
+
+	// running holds the cancel functions of jobs executing on this replica.
+	mu      sync.Mutex
+	running map[uuid.UUID]context.CancelCauseFunc
+
+	// webhooks tracks completion webhooks still being delivered.
+	webhooks sync.WaitGroup
 }
 
-func NewBroadcastService(buyerRepo buyer.PermissionsRepository, sellerRepo sellerPorts.SellerRepository, cfg *config.Config) *BroadcastService {
+var (
+	ErrJobCancelled = errors.New("broadcast job cancelled")
+	ErrJobFinished  = errors.New("broadcast job has already finished")
+
+	// ErrSigningFailed means this adapter cannot sign /search requests, typically because
+	// PRIVATE_KEY is missing or malformed. It fails the whole job rather than any one seller.
+	ErrSigningFailed = errors.New("cannot sign /search requests, check PRIVATE_KEY")
+
+	ErrInvalidCallbackURL = errors.New("invalid callback_url")
+)
+
+func NewBroadcastService(buyerRepo buyer.PermissionsRepository, sellerRepo sellerPorts.SellerRepository, events EventBus, cache caching.CacheService, cfg *config.Config) *BroadcastService {
 	return &BroadcastService{
-		buyerRepo:  buyerRepo,
-		sellerRepo: sellerRepo,
-		httpClient: resty.New().SetTimeout(60 * time.Second),
-		config:     cfg,
+		buyerRepo:     buyerRepo,
+		sellerRepo:    sellerRepo,
+		httpClient:    resty.New().SetTimeout(60 * time.Second),
+		webhookClient: newWebhookClient(cfg),
+		signer: crypto.NewRequestSigner(crypto.SignerConfig{
+			SubscriberID: cfg.SubscriberID,
+			UniqueKeyID:  cfg.UniqueKeyID,
+			PrivateKey:   cfg.PrivateKey,
+			TTL:          cfg.RequestSignatureTTL,
+			Gateway:      cfg.SignAsGateway,
+		}),
+		config:      cfg,
+		inFlight:    ratelimit.NewSemaphore(cfg.BroadcastMaxInFlight),
+		hostLimiter: ratelimit.NewHostLimiter(cfg.BroadcastHostRateLimit, cfg.BroadcastHostBurst),
+		running:     make(map[uuid.UUID]context.CancelCauseFunc),
+		events:      events,
+		cache:       cache,
 	}
 }
 
-func (s *BroadcastService) BroadcastPermissions(req broadcast.BroadcastRequest) (*buyer.PermissionsJob, error) {
+// BroadcastPermissions queues a broadcast for the bap_id in the search context. signerID is the
+// subscriber that signed the request, or "" when it was not signed; a signer may only broadcast
+// as itself.
+func (s *BroadcastService) BroadcastPermissions(req broadcast.BroadcastRequest, signerID string) (*buyer.PermissionsJob, error) {
 	ctx := context.Background() // Initialize context for logging
 
 	if req.SearchPayload == nil || req.SearchPayload.Context == nil {
@@ -46,26 +98,59 @@ func (s *BroadcastService) BroadcastPermissions(req broadcast.BroadcastRequest)
 		log.Errorf(ctx, nil, "bap_id is required in search_payload context")
 		return nil, fmt.Errorf("bap_id is required")
 	}
+	if signerID != "" && signerID != bapID {
+		log.Warnf(ctx, "Rejecting broadcast for bap_id %s signed by %s", bapID, signerID)
+		return nil, fmt.Errorf("%w: %s cannot act for %s", buyer.ErrBapIDMismatch, signerID, bapID)
+	}
 
-	// Check if BAP exists, create if not.
-	_, err := s.buyerRepo.FindBapByID(bapID)
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			now := time.Now()
-			newBap := buyer.Bap{BapID: bapID, FirstSeenAt: now, LastSeenAt: now}
-			if err := s.buyerRepo.UpsertBaps(map[string]buyer.Bap{bapID: newBap}); err != nil {
-				log.Errorf(ctx, err, "Failed to create new BAP with id %s", bapID)
-				return nil, fmt.Errorf("failed to create new BAP with id %s: %w", bapID, err)
+	// Check if BAP exists, create if not. In strict mode only BAPs subscribed in the registry
+	// may broadcast.
+	bap, err := s.buyerRepo.FindBapByID(bapID)
+	if err != nil && err != gorm.ErrRecordNotFound {
+		log.Errorf(ctx, err, "Database error when trying to find BAP by ID %s", bapID)
+		return nil, err
+	}
+	if s.config.BapRegistryMode == buyer.BapRegistryModeReport || s.config.BapRegistryMode == buyer.BapRegistryModeStrict {
+		if status := bap.RegistryStatus(time.Now(), s.config.BapRegistryMaxAge); status != "" {
+			if s.config.BapRegistryMode == buyer.BapRegistryModeStrict {
+				log.Warnf(ctx, "Rejecting broadcast for bap_id %s: %s in the registry", bapID, status)
+				return nil, fmt.Errorf("%w: %s is %s", buyer.ErrBapNotRegistered, bapID, status)
 			}
-		} else {
-			log.Errorf(ctx, err, "Database error when trying to find BAP by ID %s", bapID)
+			log.Warnf(ctx, "Broadcasting for bap_id %s although it is %s in the registry", bapID, status)
+		}
+	}
+	// A BAP only synced from the registry is recorded as seen on its first broadcast.
+	if err == gorm.ErrRecordNotFound || bap.FirstSeenAt == nil {
+		newBap := buyer.Bap{BapID: bapID}
+		if err := s.buyerRepo.UpsertBaps(map[string]buyer.Bap{bapID: newBap}); err != nil {
+			log.Errorf(ctx, err, "Failed to create new BAP with id %s", bapID)
+			return nil, fmt.Errorf("failed to create new BAP with id %s: %w", bapID, err)
+		}
+	}
+
+	var callbackURL *string
+	if req.CallbackURL != "" {
+		if err := s.validateCallbackURL(req.CallbackURL); err != nil {
+			log.Warnf(ctx, "Rejecting broadcast for bap_id %s: %v", bapID, err)
 			return nil, err
 		}
+		callbackURL = &req.CallbackURL
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		log.Errorf(ctx, err, "Failed to serialize broadcast request for bap_id %s", bapID)
+		return nil, err
 	}
 
+	// The job is persisted with its request so that a worker on any replica can pick it up.
 	job := &buyer.PermissionsJob{
-		BapID:  bapID,
-		Status: "INITIATED",
+		BapID:          bapID,
+		Status:         buyer.JobStatusInitiated,
+		TransactionID:  req.SearchPayload.Context.TransactionID,
+		MessageID:      req.SearchPayload.Context.MessageID,
+		RequestPayload: string(payload),
+		CallbackURL:    callbackURL,
 	}
 
 	if err := s.buyerRepo.CreatePermissionsJob(job); err != nil {
@@ -73,13 +158,152 @@ func (s *BroadcastService) BroadcastPermissions(req broadcast.BroadcastRequest)
 		return nil, err
 	}
 
-	log.Infof(ctx, "Initiating broadcast for bap_id %s with job_id %s", bapID, job.ID)
-	go s.startBroadcast(req, job.ID)
-
+	log.Infof(ctx, "Queued broadcast for bap_id %s with job_id %s", bapID, job.ID)
 	return job, nil
 }
 
-func (s *BroadcastService) startBroadcast(req broadcast.BroadcastRequest, jobID uuid.UUID) {
+func (s *BroadcastService) startBroadcast(ctx context.Context, req broadcast.BroadcastRequest, jobID uuid.UUID) {
+	domain := req.SearchPayload.Context.Domain
+
+	sellers, targets, err := s.resumeTargets(domain, jobID)
+	if err != nil {
+		log.Errorf(ctx, err, "Failed to load unfinished targets for broadcast job %s", jobID)
+		s.finishJob(jobID, buyer.JobStatusFailed)
+		return
+	}
+	if targets == nil {
+		sellers, targets, err = s.selectTargets(req, jobID)
+		if err != nil {
+			s.finishJob(jobID, buyer.JobStatusFailed)
+			return
+		}
+	}
+
+	if len(sellers) == 0 {
+		log.Warnf(ctx, "No sellers left to contact for job %s. Marking job as COMPLETED.", jobID)
+		s.finishJob(jobID, buyer.JobStatusCompleted)
+		return
+	}
+
+	workers := s.config.BroadcastConcurrency
+	if workers <= 0 || workers > len(sellers) {
+		workers = len(sellers)
+	}
+
+	log.Infof(ctx, "Starting broadcast to %d sellers for job %s with %d workers", len(sellers), jobID, workers)
+	pending := make(chan int, len(sellers))
+	for i := range sellers {
+		pending <- i
+	}
+	close(pending)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range pending {
+				if ctx.Err() != nil {
+					return
+				}
+				s.sendSearchRequest(ctx, sellers[i], &targets[i], req)
+			}
+		}()
+	}
+	wg.Wait()
+
+	switch {
+	case errors.Is(context.Cause(ctx), ErrJobCancelled):
+		log.Infof(ctx, "Broadcast job %s was cancelled.", jobID)
+		s.closePendingTargets(jobID, buyer.TargetOutcomeCancelled, "job cancelled")
+	case errors.Is(context.Cause(ctx), ErrSigningFailed):
+		log.Errorf(ctx, context.Cause(ctx), "Broadcast job %s cannot sign its requests. Marking job as FAILED.", jobID)
+		s.closePendingTargets(jobID, buyer.TargetOutcomeError, context.Cause(ctx).Error())
+		s.finishJob(jobID, buyer.JobStatusFailed)
+	case errors.Is(context.Cause(ctx), buyer.ErrJobLeaseLost):
+		// Whoever holds the lease now owns the remaining targets.
+		log.Warnf(ctx, "Broadcast job %s lost its lease; leaving it to the worker that holds it.", jobID)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		log.Warnf(ctx, "Broadcast job %s exceeded its deadline. Marking job as TIMED_OUT.", jobID)
+		s.closePendingTargets(jobID, buyer.TargetOutcomeTimeout, "job deadline exceeded")
+		s.finishJob(jobID, buyer.JobStatusTimedOut)
+	case ctx.Err() != nil:
+		// The worker is shutting down; unfinished targets stay pending for the next worker.
+		log.Warnf(ctx, "Broadcast job %s interrupted by shutdown; it will be resumed.", jobID)
+	default:
+		log.Infof(ctx, "Broadcast finished for job %s. All sellers have responded.", jobID)
+		s.finishJob(jobID, buyer.JobStatusCompleted)
+	}
+}
+
+// cancelRunning stops a job executing on this replica, recording cause as the reason.
+func (s *BroadcastService) cancelRunning(jobID uuid.UUID, cause error) {
+	s.mu.Lock()
+	cancel, ok := s.running[jobID]
+	s.mu.Unlock()
+	if ok {
+		cancel(cause)
+	}
+}
+
+func (s *BroadcastService) closePendingTargets(jobID uuid.UUID, outcome buyer.TargetOutcome, reason string) {
+	if err := s.buyerRepo.ClosePendingPermissionsJobTargets(jobID, outcome, reason); err != nil {
+		log.Errorf(context.Background(), err, "Failed to close pending targets for job %s", jobID)
+	}
+}
+
+// resumeTargets returns the targets of a previously started job that have not been contacted yet,
+// paired with their sellers. It returns nil targets when the job has not created any yet.
+func (s *BroadcastService) resumeTargets(domain string, jobID uuid.UUID) ([]sellerPorts.Seller, []buyer.PermissionsJobTarget, error) {
+	counts, err := s.buyerRepo.CountPermissionsJobTargets(jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(counts) == 0 {
+		return nil, nil, nil
+	}
+
+	pendingTargets, err := s.buyerRepo.GetPendingPermissionsJobTargets(jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(pendingTargets) == 0 {
+		return nil, []buyer.PermissionsJobTarget{}, nil
+	}
+
+	sellerIDs := make([]string, 0, len(pendingTargets))
+	for _, t := range pendingTargets {
+		sellerIDs = append(sellerIDs, t.SellerID)
+	}
+	found, err := s.sellerRepo.GetSellersByFilters(map[string]interface{}{"domain": domain, "seller_id": sellerIDs})
+	if err != nil {
+		return nil, nil, err
+	}
+	sellerMap := make(map[string]sellerPorts.Seller, len(found))
+	for _, sel := range found {
+		sellerMap[sel.SellerID] = sel
+	}
+
+	var sellers []sellerPorts.Seller
+	var targets []buyer.PermissionsJobTarget
+	for _, t := range pendingTargets {
+		sel, ok := sellerMap[t.SellerID]
+		if !ok {
+			failTarget(&t, buyer.TargetOutcomeError, fmt.Errorf("seller no longer present in registry"))
+			if err := s.buyerRepo.UpdatePermissionsJobTarget(&t); err != nil {
+				log.Errorf(context.Background(), err, "Failed to record missing seller %s for job %s", t.SellerID, jobID)
+			}
+			continue
+		}
+		sellers = append(sellers, sel)
+		targets = append(targets, t)
+	}
+	log.Infof(context.Background(), "Resuming broadcast job %s with %d unfinished targets", jobID, len(targets))
+	return sellers, targets, nil
+}
+
+// selectTargets picks the sellers matching the broadcast request and persists a pending target for each.
+func (s *BroadcastService) selectTargets(req broadcast.BroadcastRequest, jobID uuid.UUID) ([]sellerPorts.Seller, []buyer.PermissionsJobTarget, error) {
 	ctx := context.Background()
 	domain := req.SearchPayload.Context.Domain
 	sellerIDs := req.SellerIDs
@@ -101,54 +325,211 @@ func (s *BroadcastService) startBroadcast(req broadcast.BroadcastRequest, jobID
 	sellers, err := s.sellerRepo.GetSellersByFilters(filters)
 	if err != nil {
 		log.Errorf(ctx, err, "Failed to fetch sellers for broadcast job %s", jobID)
-		s.updateJobStatus(jobID, "FAILED")
-		return
+		return nil, nil, err
 	}
 
 	if len(sellers) == 0 {
-		log.Warnf(ctx, "No sellers found for broadcast criteria for job %s. Marking job as COMPLETED.", jobID)
-		s.updateJobStatus(jobID, "COMPLETED")
+		log.Warnf(ctx, "No sellers found for broadcast criteria for job %s.", jobID)
+		return nil, []buyer.PermissionsJobTarget{}, nil
+	}
+
+	// Skipped sellers are recorded alongside the pending ones so that the job lists them.
+	now := time.Now()
+	eligible := make([]sellerPorts.Seller, 0, len(sellers))
+	targets := make([]buyer.PermissionsJobTarget, 0, len(sellers))
+	var skipped []buyer.PermissionsJobTarget
+	for _, sel := range sellers {
+		target := buyer.PermissionsJobTarget{
+			JobID:    jobID,
+			SellerID: sel.SellerID,
+			Domain:   domain,
+			Outcome:  buyer.TargetOutcomePending,
+		}
+		if reason := s.targetSkipReason(sel, now); reason != "" {
+			target.Outcome = buyer.TargetOutcomeSkipped
+			target.SkipReason = &reason
+			skipped = append(skipped, target)
+			continue
+		}
+		eligible = append(eligible, sel)
+		targets = append(targets, target)
+	}
+	if len(skipped) > 0 {
+		log.Infof(ctx, "Skipping %d of %d sellers for broadcast job %s", len(skipped), len(sellers), jobID)
+	}
+
+	rows := make([]buyer.PermissionsJobTarget, 0, len(sellers))
+	rows = append(rows, targets...)
+	rows = append(rows, skipped...)
+	if err := s.buyerRepo.CreatePermissionsJobTargets(rows); err != nil {
+		log.Errorf(ctx, err, "Failed to create targets for broadcast job %s", jobID)
+		return nil, nil, err
+	}
+	for _, target := range skipped {
+		s.publishTargetEvent(target)
+	}
+	return eligible, targets, nil
+}
+
+func (s *BroadcastService) finishJob(jobID uuid.UUID, status string) {
+	finished, err := s.buyerRepo.FinishPermissionsJob(jobID, status)
+	if err != nil {
+		log.Errorf(context.Background(), err, "Failed to update status for job %s", jobID)
 		return
 	}
+	if finished {
+		s.publishJobFinished(jobID)
+		s.notifyJobFinishedAsync(jobID)
+	}
+}
+
+// getCallerJob loads a job on behalf of bapID. Jobs started by other BAPs are reported as
+// gorm.ErrRecordNotFound so that callers cannot probe job IDs they do not own.
+func (s *BroadcastService) getCallerJob(jobID uuid.UUID, bapID string) (*buyer.PermissionsJob, error) {
+	job, err := s.buyerRepo.GetPermissionsJobByID(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.BapID != bapID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return job, nil
+}
 
-	log.Infof(ctx, "Starting broadcast to %d sellers for job %s", len(sellers), jobID)
-	done := make(chan bool, len(sellers))
+func (s *BroadcastService) GetBroadcastStatus(jobID uuid.UUID, bapID, outcome string, limit, page, offset int) (*broadcast.BroadcastStatusResponse, error) {
+	job, err := s.getCallerJob(jobID, bapID)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, sel := range sellers {
-		go s.sendSearchRequest(sel, req, done)
+	counts, err := s.targetCounts(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := s.buyerRepo.ListPermissionsJobTargets(jobID, outcome, limit, offset)
+	if err != nil {
+		return nil, err
 	}
 
-	for i := 0; i < len(sellers); i++ {
-		<-done
+	hasMore := len(targets) > limit
+	if hasMore {
+		targets = targets[:limit] // Trim the extra record fetched for hasMore check
 	}
 
-	log.Infof(ctx, "Broadcast finished for job %s. All sellers have responded.", jobID)
-	s.updateJobStatus(jobID, "COMPLETED")
+	return &broadcast.BroadcastStatusResponse{
+		PermissionsJob: job,
+		Counts:         *counts,
+		Targets:        targets,
+		Page: sellerPorts.PageInfo{
+			Limit:   limit,
+			Page:    page,
+			HasMore: hasMore,
+		},
+	}, nil
 }
 
-func (s *BroadcastService) updateJobStatus(jobID uuid.UUID, status string) {
-	err := s.buyerRepo.UpdatePermissionsJobStatus(jobID, status)
+// targetCounts summarises the /search outcomes of a job's targets.
+func (s *BroadcastService) targetCounts(jobID uuid.UUID) (*broadcast.BroadcastTargetCounts, error) {
+	counts, err := s.buyerRepo.CountPermissionsJobTargets(jobID)
 	if err != nil {
-		log.Errorf(context.Background(), err, "Failed to update status for job %s", jobID)
+		return nil, err
+	}
+
+	attempts, retried, err := s.buyerRepo.GetPermissionsJobAttemptStats(jobID)
+	if err != nil {
+		return nil, err
 	}
+
+	summary := &broadcast.BroadcastTargetCounts{
+		Pending:   counts[buyer.TargetOutcomePending],
+		Ack:       counts[buyer.TargetOutcomeAck],
+		Nack:      counts[buyer.TargetOutcomeNack],
+		Timeout:   counts[buyer.TargetOutcomeTimeout],
+		Error:     counts[buyer.TargetOutcomeError],
+		Cancelled: counts[buyer.TargetOutcomeCancelled],
+		Skipped:   counts[buyer.TargetOutcomeSkipped],
+		Attempts:  attempts,
+		Retried:   retried,
+	}
+	for _, count := range counts {
+		summary.Total += count
+	}
+	return summary, nil
 }
 
-func (s *BroadcastService) GetBroadcastStatus(jobID uuid.UUID) (*buyer.PermissionsJob, error) {
+// CancelBroadcast stops a queued or running job. Requests in flight on this replica are
+// cancelled immediately; other replicas notice the status change on their next lease renewal.
+// Only the BAP that started the job may cancel it.
+func (s *BroadcastService) CancelBroadcast(jobID uuid.UUID, bapID string) (*buyer.PermissionsJob, error) {
+	ctx := context.Background()
+
+	if _, err := s.getCallerJob(jobID, bapID); err != nil {
+		return nil, err
+	}
+
+	cancelled, err := s.buyerRepo.FinishPermissionsJob(jobID, buyer.JobStatusCancelled)
+	if err != nil {
+		log.Errorf(ctx, err, "Failed to cancel broadcast job %s", jobID)
+		return nil, err
+	}
+	if !cancelled {
+		return nil, ErrJobFinished
+	}
+
+	s.cancelRunning(jobID, ErrJobCancelled)
+	s.publishJobFinished(jobID)
+	s.notifyJobFinishedAsync(jobID)
+
+	log.Infof(ctx, "Broadcast job %s cancelled", jobID)
 	return s.buyerRepo.GetPermissionsJobByID(jobID)
 }
 
-func (s *BroadcastService) sendSearchRequest(seller sellerPorts.Seller, req broadcast.BroadcastRequest, done chan bool) {
-	defer func() { done <- true }()
-	ctx := context.Background() // Create a new context for logging
+// searchURL builds the seller's /search endpoint from the subscriber_url synced from the registry
+func searchURL(seller sellerPorts.Seller) (*url_pkg.URL, error) {
+	if seller.URLError != nil {
+		return nil, fmt.Errorf("unusable subscriber_url %q: %s", seller.SubscriberURL, *seller.URLError)
+	}
+	raw := seller.SubscriberURL
+	// Rows synced before subscriber_url was validated hold a bare host with no url_error. They
+	// were always reached over https, and the next registry sync replaces them.
+	if raw != "" && !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+	parsedURL, err := url_pkg.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if parsedURL.Scheme == "" || parsedURL.Host == "" {
+		return nil, fmt.Errorf("subscriber_url %q is not an absolute URL", seller.SubscriberURL)
+	}
+	// Subscriber URLs usually carry a path already, so /search is appended to it.
+	parsedURL.Path = path.Join(parsedURL.Path, "search")
+	return parsedURL, nil
+}
 
+func (s *BroadcastService) sendSearchRequest(ctx context.Context, seller sellerPorts.Seller, target *buyer.PermissionsJobTarget, req broadcast.BroadcastRequest) {
 	var policy *buyer.BapAccessPolicy
 	now := time.Now()
-	expiresAt := now.Add(24 * time.Hour)
+
+	defer func() {
+		if policy != nil {
+			target.Decision = policy.Decision
+		}
+		if err := s.buyerRepo.UpdatePermissionsJobTarget(target); err != nil {
+			log.Errorf(ctx, err, "Failed to record broadcast outcome for seller %s in job %s", seller.SellerID, target.JobID)
+			return
+		}
+		if target.Outcome != buyer.TargetOutcomePending {
+			s.publishTargetEvent(*target)
+		}
+	}()
 
 	if s.config.MockSellerResponse {
 		log.Infof(ctx, "MOCK_SELLER_RESPONSE is true. Returning mock ACK for seller %s", seller.SellerID)
 		// Construct a mock ACK response to simulate success
 		reason := "ALLOWED for testing"
+		target.Outcome = buyer.TargetOutcomeAck
 		policy = &buyer.BapAccessPolicy{
 			SellerID:       seller.SellerID,
 			Domain:         req.SearchPayload.Context.Domain,
@@ -156,7 +537,7 @@ func (s *BroadcastService) sendSearchRequest(seller sellerPorts.Seller, req broa
 			Decision:       sellerPorts.DecisionAllowed,
 			DecisionSource: sellerPorts.SourceManualOverride,
 			DecidedAt:      now,
-			ExpiresAt:      &expiresAt,
+			ExpiresAt:      s.policyExpiry(sellerPorts.DecisionAllowed, now, ""),
 			Reason:         &reason,
 		}
 	} else {
@@ -166,45 +547,99 @@ func (s *BroadcastService) sendSearchRequest(seller sellerPorts.Seller, req broa
 			"message": req.SearchPayload.Message,
 		}
 
-		// Ensure the URL has a scheme.
-		url := seller.SubscriberURL
-		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-			url = "https://" + url
-		}
-		// It seems some subscriber URLs might already contain a path.
-		// We need to correctly append /search. Let's use url.Parse.
-		parsedURL, err := url_pkg.Parse(url)
+		parsedURL, err := searchURL(seller)
 		if err != nil {
 			log.Errorf(ctx, err, "Failed to parse seller SubscriberURL: %s", seller.SubscriberURL)
+			failTarget(target, buyer.TargetOutcomeError, err)
 			return
 		}
-		parsedURL.Path = path.Join(parsedURL.Path, "search")
 		finalURL := parsedURL.String()
+		target.URL = finalURL
 
-		log.Infof(ctx, "Sending /search request to seller %s at %s", seller.SellerID, finalURL)
+		body, err := json.Marshal(searchReqPayload)
+		if err != nil {
+			log.Errorf(ctx, err, "Failed to serialize /search payload for seller %s", seller.SellerID)
+			failTarget(target, buyer.TargetOutcomeError, err)
+			return
+		}
+
+		var resp *resty.Response
+		for {
+			log.Infof(ctx, "Sending /search request to seller %s at %s (attempt %d)", seller.SellerID, finalURL, target.AttemptCount+1)
+			resp, err = s.postSearch(ctx, parsedURL, body, target)
+
+			retryAfter, retryable := shouldRetry(ctx, resp, err)
+			if !retryable || target.AttemptCount >= s.config.BroadcastMaxAttempts {
+				break
+			}
 
-		resp, err := s.httpClient.R().
-			SetBody(searchReqPayload).
-			Post(finalURL)
+			delay := s.backoff(target.AttemptCount, retryAfter)
+			log.Warnf(ctx, "Transient failure from seller %s on attempt %d, retrying in %s", seller.SellerID, target.AttemptCount, delay)
+			if !sleepContext(ctx, delay) {
+				break
+			}
+		}
+
+		// Neither a local signing failure nor the job ending underneath us says anything about
+		// the seller, so no policy is written.
+		if errors.Is(err, ErrSigningFailed) {
+			log.Errorf(ctx, err, "Failed to sign /search request for seller %s; stopping job %s", seller.SellerID, target.JobID)
+			failTarget(target, buyer.TargetOutcomeError, err)
+			s.cancelRunning(target.JobID, err)
+			return
+		}
+		if ctx.Err() != nil {
+			switch {
+			case errors.Is(context.Cause(ctx), ErrJobCancelled):
+				failTarget(target, buyer.TargetOutcomeCancelled, context.Cause(ctx))
+			case errors.Is(ctx.Err(), context.DeadlineExceeded):
+				failTarget(target, buyer.TargetOutcomeTimeout, fmt.Errorf("job deadline exceeded"))
+			default:
+				target.Outcome = buyer.TargetOutcomePending
+			}
+			return
+		}
 
 		if err != nil {
-			log.Errorf(ctx, err, "Failed to send /search request to seller %s", seller.SellerID)
+			log.Errorf(ctx, err, "Failed to send /search request to seller %s after %d attempts", seller.SellerID, target.AttemptCount)
+			if isTimeout(err) {
+				failTarget(target, buyer.TargetOutcomeTimeout, err)
+			} else {
+				failTarget(target, buyer.TargetOutcomeError, err)
+			}
+			reason := fmt.Sprintf("Failed to reach seller after %d attempts: %v", target.AttemptCount, err)
+			policy = &buyer.BapAccessPolicy{
+				SellerID:  seller.SellerID,
+				Domain:    req.SearchPayload.Context.Domain,
+				BapID:     req.SearchPayload.Context.BapID,
+				Decision:  sellerPorts.DecisionErrorOccurred,
+				Reason:    &reason,
+				DecidedAt: now,
+				ExpiresAt: s.policyExpiry(sellerPorts.DecisionErrorOccurred, now, ""),
+			}
+			s.upsertPolicy(ctx, policy)
 			return
 		}
 
+		statusCode := resp.StatusCode()
+		target.HTTPStatus = &statusCode
+
 		log.Infof(ctx, "Received response from seller %s: Status %d, Body: %s", seller.SellerID, resp.StatusCode(), string(resp.Body()))
 
 		if resp.IsSuccess() {
 			var ackResponse broadcast.AckResponse
 			if err := json.Unmarshal(resp.Body(), &ackResponse); err == nil && ackResponse.Message != nil && ackResponse.Message.Ack != nil && ackResponse.Message.Ack.Status == "ACK" {
-				log.Infof(ctx, "Received ACK from seller %s for bap_id %s. Creating GRANTED policy.", seller.SellerID, req.SearchPayload.Context.BapID)
+				// An ACK only means the seller accepted the search; the decision is settled by its on_search callback.
+				log.Infof(ctx, "Received ACK from seller %s for bap_id %s. Creating PENDING policy until on_search arrives.", seller.SellerID, req.SearchPayload.Context.BapID)
+				target.Outcome = buyer.TargetOutcomeAck
 				policy = &buyer.BapAccessPolicy{
-					SellerID:  seller.SellerID,
-					Domain:    req.SearchPayload.Context.Domain,
-					BapID:     req.SearchPayload.Context.BapID,
-					Decision:  sellerPorts.DecisionAllowed,
-					DecidedAt: now,
-					ExpiresAt: &expiresAt,
+					SellerID:       seller.SellerID,
+					Domain:         req.SearchPayload.Context.Domain,
+					BapID:          req.SearchPayload.Context.BapID,
+					Decision:       sellerPorts.DecisionPending,
+					DecisionSource: sellerPorts.SourceSellerAck,
+					DecidedAt:      now,
+					ExpiresAt:      s.policyExpiry(sellerPorts.DecisionPending, now, ""),
 				}
 			} else {
 
@@ -215,23 +650,29 @@ func (s *BroadcastService) sendSearchRequest(seller sellerPorts.Seller, req broa
 					if nackResponse.Error != nil {
 						reason = nackResponse.Error.Message
 					}
+					target.Outcome = buyer.TargetOutcomeNack
+					target.Error = &reason
 					policy = &buyer.BapAccessPolicy{
-						SellerID:  seller.SellerID,
-						Domain:    req.SearchPayload.Context.Domain,
-						BapID:     req.SearchPayload.Context.BapID,
-						Decision:  sellerPorts.DecisionDenied,
-						Reason:    &reason,
-						DecidedAt: now,
-						ExpiresAt: &expiresAt,
+						SellerID:       seller.SellerID,
+						Domain:         req.SearchPayload.Context.Domain,
+						BapID:          req.SearchPayload.Context.BapID,
+						Decision:       sellerPorts.DecisionDenied,
+						DecisionSource: sellerPorts.SourceSellerNack,
+						Reason:         &reason,
+						DecidedAt:      now,
+						ExpiresAt:      s.policyExpiry(sellerPorts.DecisionDenied, now, sellerPolicyTTL(nackResponse.Message.Ack.Tags)),
 					}
 				} else {
 
 					log.Warnf(ctx, "Received success status from seller %s, but could not decode ACK/NACK from body: %s", seller.SellerID, string(resp.Body()))
+					failTarget(target, buyer.TargetOutcomeError, fmt.Errorf("could not decode ACK/NACK from response body"))
 				}
 			}
 		} else {
 			log.Errorf(ctx, nil, "Received non-success status (%d) from seller %s", resp.StatusCode(), seller.SellerID)
 			reason := fmt.Sprintf("Received non-success status %d from seller. Body: %s", resp.StatusCode(), string(resp.Body()))
+			target.Outcome = buyer.TargetOutcomeError
+			target.Error = &reason
 			policy = &buyer.BapAccessPolicy{
 				SellerID:  seller.SellerID,
 				Domain:    req.SearchPayload.Context.Domain,
@@ -239,16 +680,69 @@ func (s *BroadcastService) sendSearchRequest(seller sellerPorts.Seller, req broa
 				Decision:  sellerPorts.DecisionErrorOccurred,
 				Reason:    &reason,
 				DecidedAt: now,
-				ExpiresAt: &expiresAt,
+				ExpiresAt: s.policyExpiry(sellerPorts.DecisionErrorOccurred, now, ""),
 			}
 		}
 	}
 
 	if policy != nil {
-		if err := s.buyerRepo.UpsertBapAccessPolicies([]buyer.BapAccessPolicy{*policy}); err != nil {
-			log.Errorf(ctx, err, "Failed to upsert BapAccessPolicy for seller %s and bap_id %s", seller.SellerID, req.SearchPayload.Context.BapID)
-		} else {
-			log.Infof(ctx, "Successfully upserted BapAccessPolicy for seller %s and bap_id %s with decision %s", seller.SellerID, req.SearchPayload.Context.BapID, policy.Decision)
-		}
+		s.upsertPolicy(ctx, policy)
+	}
+}
+
+func (s *BroadcastService) upsertPolicy(ctx context.Context, policy *buyer.BapAccessPolicy) {
+	stored, err := s.buyerRepo.UpsertBapAccessPolicies([]buyer.BapAccessPolicy{*policy})
+	switch {
+	case err != nil:
+		log.Errorf(ctx, err, "Failed to upsert BapAccessPolicy for seller %s and bap_id %s", policy.SellerID, policy.BapID)
+	case !stored[0]:
+		log.Infof(ctx, "Kept the existing BapAccessPolicy for seller %s and bap_id %s over %s", policy.SellerID, policy.BapID, policy.Decision)
+	default:
+		log.Infof(ctx, "Successfully upserted BapAccessPolicy for seller %s and bap_id %s with decision %s", policy.SellerID, policy.BapID, policy.Decision)
+	}
+}
+
+// postSearch signs and sends a single /search attempt, honouring the per-host rate limit and
+// the global in-flight cap, and records the attempt on the target.
+func (s *BroadcastService) postSearch(ctx context.Context, searchURL *url_pkg.URL, body []byte, target *buyer.PermissionsJobTarget) (*resty.Response, error) {
+	// Sign the exact bytes we send so the seller's digest matches. Each attempt is signed
+	// afresh so retries are not rejected for an expired signature.
+	authHeaders, err := s.signer.Headers(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrSigningFailed, err)
+	}
+
+	if err := s.hostLimiter.Wait(ctx, searchURL.Host); err != nil {
+		return nil, err
+	}
+	if err := s.inFlight.Acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer s.inFlight.Release()
+
+	target.AttemptCount++
+	start := time.Now()
+	resp, err := s.httpClient.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeaders(authHeaders).
+		SetBody(body).
+		Post(searchURL.String())
+	latency := time.Since(start).Milliseconds()
+	target.LatencyMs = &latency
+	return resp, err
+}
+
+func failTarget(target *buyer.PermissionsJobTarget, outcome buyer.TargetOutcome, err error) {
+	message := err.Error()
+	target.Outcome = outcome
+	target.Error = &message
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
 	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
 }
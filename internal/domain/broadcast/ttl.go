@@ -0,0 +1,63 @@
+package broadcast
+
+import (
+	"context"
+	"time"
+
+	"adapter/internal/ports/broadcast"
+	sellerPorts "adapter/internal/ports/seller"
+	"adapter/internal/shared/log"
+	"adapter/internal/shared/utils"
+)
+
+// Sellers can override how long their decision holds with a ttl item in the bap_access tag,
+// e.g. {"code": "ttl", "value": "P7D"}. Without one, the configured default applies; the context
+// ttl is the lifetime of the message, not of the decision, so it is not used here.
+const ttlTagCode = "ttl"
+
+// defaultPolicyTTL is the configured lifetime of a policy with the given decision.
+func (s *BroadcastService) defaultPolicyTTL(decision sellerPorts.AccessDecision) time.Duration {
+	switch decision {
+	case sellerPorts.DecisionAllowed:
+		return s.config.PolicyTTLAllowed
+	case sellerPorts.DecisionDenied:
+		return s.config.PolicyTTLDenied
+	case sellerPorts.DecisionPending:
+		return s.config.PolicyTTLPending
+	default:
+		return s.config.PolicyTTLErrorOccurred
+	}
+}
+
+// policyExpiry returns when a policy decided at decidedAt expires. A valid seller-supplied
+// ISO 8601 ttl replaces the configured default, capped at POLICY_TTL_MAX.
+func (s *BroadcastService) policyExpiry(decision sellerPorts.AccessDecision, decidedAt time.Time, sellerTTL string) *time.Time {
+	ttl := s.defaultPolicyTTL(decision)
+	if sellerTTL != "" {
+		if parsed, err := utils.ParseISODuration(sellerTTL); err != nil || parsed <= 0 {
+			log.Warnf(context.Background(), "Ignoring seller-supplied policy ttl %q: not a positive ISO 8601 duration", sellerTTL)
+		} else {
+			ttl = parsed
+			if s.config.PolicyTTLMax > 0 && ttl > s.config.PolicyTTLMax {
+				ttl = s.config.PolicyTTLMax
+			}
+		}
+	}
+	expiresAt := decidedAt.Add(ttl)
+	return &expiresAt
+}
+
+// sellerPolicyTTL picks the seller's ttl from its bap_access tag, or "" when there is none.
+func sellerPolicyTTL(tags []*broadcast.Tag) string {
+	for _, tag := range tags {
+		if tag == nil || tag.Code != accessPolicyTagCode {
+			continue
+		}
+		for _, item := range tag.List {
+			if item != nil && item.Code == ttlTagCode && item.Value != "" {
+				return item.Value
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,197 @@
+package broadcast
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"adapter/internal/config"
+
+	"adapter/internal/ports/broadcast"
+	"adapter/internal/ports/buyer"
+	"adapter/internal/shared/log"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+)
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which net.IP does not
+// classify as private.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// newWebhookClient builds the client used for callback_url deliveries. Unless private networks
+// are allowed, the dialer refuses non-public addresses after DNS resolution, so a callback host
+// cannot be pointed at internal services once it has been accepted.
+func newWebhookClient(cfg *config.Config) *resty.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if !cfg.WebhookAllowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return fmt.Errorf("%w: %s is not a public address", ErrInvalidCallbackURL, host)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return resty.New().
+		SetTransport(transport).
+		SetTimeout(30 * time.Second).
+		SetRedirectPolicy(resty.NoRedirectPolicy())
+}
+
+// validateCallbackURL accepts absolute http(s) URLs without credentials whose host is on the
+// configured allowlist and, unless private networks are allowed, is not a loopback, private or
+// link-local address.
+func (s *BroadcastService) validateCallbackURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return fmt.Errorf("%w: must be an absolute http(s) URL", ErrInvalidCallbackURL)
+	}
+	if parsed.User != nil {
+		return fmt.Errorf("%w: must not carry credentials", ErrInvalidCallbackURL)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(parsed.Hostname(), "."))
+	if len(s.config.WebhookAllowedHosts) > 0 && !hostAllowed(host, s.config.WebhookAllowedHosts) {
+		return fmt.Errorf("%w: host %s is not allowed", ErrInvalidCallbackURL, host)
+	}
+	if s.config.WebhookAllowPrivateNetworks {
+		return nil
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: host %s is not public", ErrInvalidCallbackURL, host)
+	}
+	if ip := net.ParseIP(host); ip != nil && !isPublicIP(ip) {
+		return fmt.Errorf("%w: host %s is not public", ErrInvalidCallbackURL, host)
+	}
+	return nil
+}
+
+// hostAllowed matches host against allowlist entries and their subdomains.
+func hostAllowed(host string, allowed []string) bool {
+	for _, entry := range allowed {
+		entry = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(entry), "."))
+		if entry == "" {
+			continue
+		}
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip))
+}
+
+// notifyJobFinishedAsync delivers the completion webhook in the background so its retries do
+// not hold up the worker. RunWorkers waits for outstanding deliveries before returning.
+func (s *BroadcastService) notifyJobFinishedAsync(jobID uuid.UUID) {
+	s.webhooks.Add(1)
+	go func() {
+		defer s.webhooks.Done()
+		s.notifyJobFinished(jobID)
+	}()
+}
+
+// notifyJobFinished POSTs a signed summary of a finished job to its callback_url, retrying
+// with backoff and logging every attempt in permissions_job_webhook_deliveries.
+func (s *BroadcastService) notifyJobFinished(jobID uuid.UUID) {
+	ctx := context.Background()
+
+	job, err := s.buyerRepo.GetPermissionsJobByID(jobID)
+	if err != nil {
+		log.Errorf(ctx, err, "Failed to load job %s for webhook notification", jobID)
+		return
+	}
+	if job.CallbackURL == nil || *job.CallbackURL == "" {
+		return
+	}
+
+	outcomes, err := s.targetCounts(jobID)
+	if err != nil {
+		log.Errorf(ctx, err, "Failed to count outcomes for job %s webhook", jobID)
+		return
+	}
+
+	body, err := json.Marshal(broadcast.BroadcastWebhookPayload{
+		JobID:      job.ID.String(),
+		BapID:      job.BapID,
+		Status:     job.Status,
+		Outcomes:   *outcomes,
+		FinishedAt: job.FinishedAt,
+	})
+	if err != nil {
+		log.Errorf(ctx, err, "Failed to serialize webhook payload for job %s", jobID)
+		return
+	}
+
+	url := *job.CallbackURL
+	for attempt := 1; attempt <= s.config.WebhookMaxAttempts; attempt++ {
+		delivery := &buyer.WebhookDelivery{JobID: jobID, URL: url, Attempt: attempt}
+		retryAfter, retryable := s.deliverWebhook(ctx, url, body, delivery)
+
+		if err := s.buyerRepo.CreateWebhookDelivery(delivery); err != nil {
+			log.Errorf(ctx, err, "Failed to log webhook delivery for job %s", jobID)
+		}
+		if delivery.Delivered {
+			log.Infof(ctx, "Delivered completion webhook for job %s to %s", jobID, url)
+			return
+		}
+		if !retryable || attempt == s.config.WebhookMaxAttempts {
+			break
+		}
+		sleepContext(ctx, backoffDelay(s.config.WebhookRetryBaseDelay, s.config.WebhookRetryMaxDelay, attempt, retryAfter))
+	}
+	log.Warnf(ctx, "Giving up on completion webhook for job %s to %s", jobID, url)
+}
+
+func (s *BroadcastService) deliverWebhook(ctx context.Context, url string, body []byte, delivery *buyer.WebhookDelivery) (retryAfter time.Duration, retryable bool) {
+	recordError := func(err error) {
+		message := err.Error()
+		delivery.Error = &message
+	}
+
+	authHeaders, err := s.signer.Headers(body)
+	if err != nil {
+		recordError(err)
+		return 0, false
+	}
+
+	resp, err := s.webhookClient.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeaders(authHeaders).
+		SetBody(body).
+		Post(url)
+	if err != nil {
+		recordError(err)
+		return shouldRetry(ctx, nil, err)
+	}
+
+	statusCode := resp.StatusCode()
+	delivery.StatusCode = &statusCode
+	if resp.IsSuccess() {
+		delivery.Delivered = true
+		return 0, false
+	}
+	recordError(fmt.Errorf("callback responded with status %d", statusCode))
+	return shouldRetry(ctx, resp, nil)
+}
@@ -0,0 +1,134 @@
+package broadcast
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"adapter/internal/ports/broadcast"
+	"adapter/internal/ports/buyer"
+	"adapter/internal/shared/log"
+)
+
+// RunWorkers polls the permissions_jobs table for queued or abandoned jobs and runs them
+// until ctx is cancelled. Jobs are claimed with row locks, so any number of API replicas
+// can run workers against the same database.
+func (s *BroadcastService) RunWorkers(ctx context.Context) {
+	workerID := s.config.WorkerID
+	if workerID == "" {
+		hostname, _ := os.Hostname()
+		workerID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	}
+
+	workers := s.config.BroadcastWorkers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	log.Infof(ctx, "Starting %d broadcast workers as %s", workers, workerID)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			s.workerLoop(ctx, fmt.Sprintf("%s/%d", workerID, n))
+		}(i)
+	}
+	wg.Wait()
+	s.webhooks.Wait()
+	log.Info(ctx, "Broadcast workers stopped")
+}
+
+func (s *BroadcastService) workerLoop(ctx context.Context, workerID string) {
+	ticker := time.NewTicker(s.config.BroadcastPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain everything that is claimable before waiting for the next tick.
+		for ctx.Err() == nil {
+			job, err := s.buyerRepo.ClaimPermissionsJob(workerID, s.config.BroadcastJobLease)
+			if err != nil {
+				log.Errorf(ctx, err, "Worker %s failed to claim a permissions job", workerID)
+				break
+			}
+			if job == nil {
+				break
+			}
+			s.runJob(ctx, workerID, job)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runJob executes a claimed job while keeping its lease alive.
+func (s *BroadcastService) runJob(ctx context.Context, workerID string, job *buyer.PermissionsJob) {
+	log.Infof(ctx, "Worker %s picked up broadcast job %s", workerID, job.ID)
+
+	var req broadcast.BroadcastRequest
+	if err := json.Unmarshal([]byte(job.RequestPayload), &req); err != nil || req.SearchPayload == nil || req.SearchPayload.Context == nil {
+		log.Errorf(ctx, err, "Broadcast job %s has no usable request payload", job.ID)
+		s.finishJob(job.ID, buyer.JobStatusFailed)
+		return
+	}
+
+	// Resumed jobs keep the deadline of their first run.
+	startedAt := time.Now()
+	if job.StartedAt != nil {
+		startedAt = *job.StartedAt
+	}
+	jobCtx, cancelJob := context.WithCancelCause(ctx)
+	defer cancelJob(nil)
+	jobCtx, cancelDeadline := context.WithDeadline(jobCtx, startedAt.Add(s.config.BroadcastJobTimeout))
+	defer cancelDeadline()
+
+	s.mu.Lock()
+	s.running[job.ID] = cancelJob
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.running, job.ID)
+		s.mu.Unlock()
+	}()
+
+	stopHeartbeat := make(chan struct{})
+	defer close(stopHeartbeat)
+	go func() {
+		ticker := time.NewTicker(s.config.BroadcastJobLease / 3)
+		defer ticker.Stop()
+		renewedAt := time.Now()
+		for {
+			select {
+			case <-stopHeartbeat:
+				return
+			case <-ticker.C:
+				// Once the lease is gone another worker may claim the job, so stop sending /search
+				// requests rather than race it. Renewal errors are tolerated until the lease is
+				// close enough to lapsing that a claim could slip in before the next tick.
+				if err := s.buyerRepo.RenewPermissionsJobLease(job.ID, workerID, s.config.BroadcastJobLease); err != nil {
+					log.Errorf(ctx, err, "Failed to renew lease for broadcast job %s", job.ID)
+					if errors.Is(err, buyer.ErrJobLeaseLost) || time.Since(renewedAt) >= s.config.BroadcastJobLease*2/3 {
+						cancelJob(buyer.ErrJobLeaseLost)
+						return
+					}
+					continue
+				}
+				renewedAt = time.Now()
+				// Picks up cancellations requested through another replica.
+				if current, err := s.buyerRepo.GetPermissionsJobByID(job.ID); err == nil && current.Status == buyer.JobStatusCancelled {
+					cancelJob(ErrJobCancelled)
+				}
+			}
+		}
+	}()
+
+	s.startBroadcast(jobCtx, req, job.ID)
+}
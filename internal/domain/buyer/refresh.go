@@ -0,0 +1,89 @@
+package buyer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	buyerPorts "adapter/internal/ports/buyer"
+	"adapter/internal/shared/log"
+)
+
+// RefreshExpiringPolicies re-probes active sellers whose policies for recently active BAPs are
+// about to expire or expired recently. Probes are queued as one broadcast per BAP and domain and
+// are capped per run and per seller so that a single run cannot flood a seller. Probed policies
+// are marked so that a seller that never answers backs off instead of filling every run.
+// Cancelling ctx stops the run before its next broadcast is queued.
+func (s *BuyerService) RefreshExpiringPolicies(ctx context.Context) (*buyerPorts.PolicyRefreshSummary, error) {
+	if s.refresher == nil {
+		return nil, fmt.Errorf("no policy refresher configured")
+	}
+
+	now := time.Now()
+	maxProbes := s.config.PolicyRefreshMaxProbes
+	// Fetch more candidates than we can probe so that the per-seller cap does not starve the run.
+	policies, err := s.repo.GetExpiringBapAccessPolicies(ctx, buyerPorts.ExpiringPolicyQuery{
+		ExpiresAfter:  now.Add(-s.config.PolicyRefreshLookback),
+		ExpiresBefore: now.Add(s.config.PolicyRefreshWindow),
+		ActiveSince:   now.Add(-s.config.PolicyRefreshActiveWithin),
+		ProbedBefore:  now.Add(-s.config.PolicyRefreshRetryAfter),
+		Limit:         maxProbes * 2,
+	})
+	if err != nil {
+		log.Errorf(ctx, err, "Failed to fetch expiring policies")
+		return nil, err
+	}
+
+	summary := &buyerPorts.PolicyRefreshSummary{Candidates: len(policies), JobIDs: []string{}}
+	type bapDomain struct{ bapID, domain string }
+	batches := make(map[bapDomain][]string)
+	perSeller := make(map[string]int)
+	for _, policy := range policies {
+		if summary.Probes >= maxProbes {
+			summary.SkippedRunLimit++
+			continue
+		}
+		if perSeller[policy.SellerID] >= s.config.PolicyRefreshMaxPerSeller {
+			summary.SkippedSellerLimit++
+			continue
+		}
+		perSeller[policy.SellerID]++
+		summary.Probes++
+		key := bapDomain{policy.BapID, policy.Domain}
+		batches[key] = append(batches[key], policy.SellerID)
+	}
+
+	keys := make([]bapDomain, 0, len(batches))
+	for key := range batches {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].bapID != keys[j].bapID {
+			return keys[i].bapID < keys[j].bapID
+		}
+		return keys[i].domain < keys[j].domain
+	})
+
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			log.Warnf(ctx, "Policy refresh cancelled after queueing %d jobs", len(summary.JobIDs))
+			return summary, err
+		}
+		sellerIDs := batches[key]
+		job, err := s.refresher.EnqueueRefresh(key.bapID, key.domain, sellerIDs)
+		if err != nil {
+			log.Errorf(ctx, err, "Failed to enqueue policy refresh for bap_id %s in domain %s", key.bapID, key.domain)
+			summary.FailedProbes += len(sellerIDs)
+			continue
+		}
+		summary.JobIDs = append(summary.JobIDs, job.ID.String())
+		if err := s.repo.MarkBapAccessPoliciesProbed(ctx, key.bapID, key.domain, sellerIDs, now); err != nil {
+			log.Errorf(ctx, err, "Failed to mark policies of bap_id %s in domain %s as probed", key.bapID, key.domain)
+		}
+	}
+
+	log.Infof(ctx, "Policy refresh queued %d probes in %d jobs (%d candidates, %d skipped by seller limit, %d by run limit)",
+		summary.Probes-summary.FailedProbes, len(summary.JobIDs), summary.Candidates, summary.SkippedSellerLimit, summary.SkippedRunLimit)
+	return summary, nil
+}
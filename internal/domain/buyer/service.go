@@ -1,22 +1,44 @@
 package buyer
 
 import (
+	"context"
+	"fmt"
+
+	"adapter/internal/config"
 	buyerPorts "adapter/internal/ports/buyer"
 	sellerPorts "adapter/internal/ports/seller"
+	"adapter/internal/shared/log"
 
 	"gorm.io/gorm"
 	"time"
 )
 
+// PolicyRefresher queues a broadcast that asks the given sellers for a fresh decision
+type PolicyRefresher interface {
+	EnqueueRefresh(bapID, domain string, sellerIDs []string) (*buyerPorts.PermissionsJob, error)
+}
+
 type BuyerService struct {
-	repo buyerPorts.PermissionsRepository
+	repo      buyerPorts.PermissionsRepository
+	refresher PolicyRefresher
+	config    *config.Config
 }
 
-func NewBuyerService(repo buyerPorts.PermissionsRepository) *BuyerService {
-	return &BuyerService{repo: repo}
+func NewBuyerService(repo buyerPorts.PermissionsRepository, refresher PolicyRefresher, cfg *config.Config) *BuyerService {
+	return &BuyerService{repo: repo, refresher: refresher, config: cfg}
 }
 
-func (s *BuyerService) UpdateBapAccessPermissions(updates []sellerPorts.SellerPermissionsUpdateRequest) ([]sellerPorts.SellerPermissionsUpdateResponse, error) {
+// UpdateBapAccessPermissions stores the given decisions. signerID is the subscriber that signed
+// the request, or "" when it was not signed; a signer may only write policies for its own bap_id.
+func (s *BuyerService) UpdateBapAccessPermissions(updates []sellerPorts.SellerPermissionsUpdateRequest, signerID string) ([]sellerPorts.SellerPermissionsUpdateResponse, error) {
+	if signerID != "" {
+		for _, update := range updates {
+			if update.BapID != signerID {
+				return nil, fmt.Errorf("%w: %s cannot act for %s", buyerPorts.ErrBapIDMismatch, signerID, update.BapID)
+			}
+		}
+	}
+
 	var results []sellerPorts.SellerPermissionsUpdateResponse
 	var policiesToUpsert []buyerPorts.BapAccessPolicy
 	bapsToUpsert := make(map[string]buyerPorts.Bap)
@@ -45,7 +67,7 @@ func (s *BuyerService) UpdateBapAccessPermissions(updates []sellerPorts.SellerPe
 			Domain:   update.Domain,
 			BapID:    update.BapID,
 			Decision: update.Decision,
-			Stored:   false, // Will be set to true after successful DB operation
+			Stored:   false, // Set from what the upsert actually wrote
 		})
 	}
 
@@ -54,41 +76,53 @@ func (s *BuyerService) UpdateBapAccessPermissions(updates []sellerPorts.SellerPe
 		return results, err
 	}
 
-	// Upsert the access policies
-	if err := s.repo.UpsertBapAccessPolicies(policiesToUpsert); err != nil {
+	// A policy kept by the conflict rules, such as a valid manual override, is reported unstored.
+	stored, err := s.repo.UpsertBapAccessPolicies(policiesToUpsert)
+	if err != nil {
 		return results, err
 	}
-
-	// Mark all as stored if we reach here without an error
 	for i := range results {
-		results[i].Stored = true
+		results[i].Stored = stored[i]
 	}
 
 	return results, nil
 }
 
-func (s *BuyerService) QueryBapAccessPermissions(req buyerPorts.BapPermissionsQueryRequest) (*buyerPorts.BapPermissionsQueryResponse, error) {
+// QueryBapAccessPermissions reports the stored decisions for req.BapID. signerID is the subscriber
+// that signed the request, or "" when it was not signed; a signer may only query its own bap_id.
+func (s *BuyerService) QueryBapAccessPermissions(req buyerPorts.BapPermissionsQueryRequest, signerID string) (*buyerPorts.BapPermissionsQueryResponse, error) {
+	if signerID != "" && signerID != req.BapID {
+		return nil, fmt.Errorf("%w: %s cannot act for %s", buyerPorts.ErrBapIDMismatch, signerID, req.BapID)
+	}
+
 	bapStatus := ""
 	bap, err := s.repo.FindBapByID(req.BapID)
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			bapStatus = "NEW_BAP"
-			// Create the BAP
-			bapsToUpsert := map[string]buyerPorts.Bap{req.BapID: {BapID: req.BapID}}
-			if err := s.repo.UpsertBaps(bapsToUpsert); err != nil {
-				return nil, err
-			}
-		} else {
-			return nil, err
-		}
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	// In strict mode an unknown bap_id is rejected before it is recorded in baps.
+	registryStatus := ""
+	if s.config.BapRegistryMode == buyerPorts.BapRegistryModeReport || s.config.BapRegistryMode == buyerPorts.BapRegistryModeStrict {
+		registryStatus = bap.RegistryStatus(time.Now(), s.config.BapRegistryMaxAge)
+	}
+	if registryStatus != "" && s.config.BapRegistryMode == buyerPorts.BapRegistryModeStrict {
+		return nil, fmt.Errorf("%w: %s is %s", buyerPorts.ErrBapNotRegistered, req.BapID, registryStatus)
+	}
+
+	// A BAP only synced from the registry has not called the adapter before, so it is still new.
+	if err == gorm.ErrRecordNotFound || bap.FirstSeenAt == nil {
+		bapStatus = buyerPorts.BapStatusNew
 	} else {
-		bapStatus = "EXISTING_BAP"
-		// Update last_seen_at
-		bap.LastSeenAt = time.Now()
-		bapsToUpsert := map[string]buyerPorts.Bap{req.BapID: *bap}
-		if err := s.repo.UpsertBaps(bapsToUpsert); err != nil {
-			return nil, err
-		}
+		bapStatus = buyerPorts.BapStatusExisting
+	}
+	// Creates the BAP, or sets first_seen_at and bumps last_seen_at
+	bapsToUpsert := map[string]buyerPorts.Bap{req.BapID: {BapID: req.BapID}}
+	if err := s.repo.UpsertBaps(bapsToUpsert); err != nil {
+		return nil, err
+	}
+
+	if registryStatus != "" {
+		bapStatus = registryStatus
 	}
 
 	policies, err := s.repo.QueryBapAccessPolicies(req.BapID, req.Domain, req.SellerIDs)
@@ -101,14 +135,24 @@ func (s *BuyerService) QueryBapAccessPermissions(req buyerPorts.BapPermissionsQu
 		policyMap[p.SellerID] = p
 	}
 
+	now := time.Now()
+	needsRefresh := []string{}
 	var permissions []sellerPorts.SellerPermissionDetail
 	for _, sellerID := range req.SellerIDs {
 		if policy, ok := policyMap[sellerID]; ok {
+			decision := string(policy.Decision)
+			if policy.ExpiresAt != nil && !policy.ExpiresAt.After(now) {
+				needsRefresh = append(needsRefresh, sellerID)
+				if req.OmitExpired {
+					continue
+				}
+				decision = string(sellerPorts.DecisionExpired)
+			}
 			permissions = append(permissions, sellerPorts.SellerPermissionDetail{
 				SellerID:       policy.SellerID,
 				Domain:         policy.Domain,
 				BapID:          policy.BapID,
-				Decision:       string(policy.Decision),
+				Decision:       decision,
 				DecisionSource: (*string)(&policy.DecisionSource),
 				DecidedAt:      &policy.DecidedAt,
 				ExpiresAt:      policy.ExpiresAt,
@@ -123,9 +167,23 @@ func (s *BuyerService) QueryBapAccessPermissions(req buyerPorts.BapPermissionsQu
 		}
 	}
 
-	return &buyerPorts.BapPermissionsQueryResponse{
-		BapStatus:   bapStatus,
-		Domain:      req.Domain,
-		Permissions: permissions,
-	}, nil
+	response := &buyerPorts.BapPermissionsQueryResponse{
+		BapStatus:    bapStatus,
+		Domain:       req.Domain,
+		Permissions:  permissions,
+		NeedsRefresh: needsRefresh,
+	}
+
+	// A failed refresh does not fail the query; the caller still gets needs_refresh.
+	if req.AutoRefresh && len(needsRefresh) > 0 && s.refresher != nil {
+		job, err := s.refresher.EnqueueRefresh(req.BapID, req.Domain, needsRefresh)
+		if err != nil {
+			log.Errorf(context.Background(), err, "Failed to enqueue policy refresh for bap_id %s", req.BapID)
+		} else {
+			jobID := job.ID.String()
+			response.RefreshJobID = &jobID
+		}
+	}
+
+	return response, nil
 }
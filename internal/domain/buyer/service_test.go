@@ -0,0 +1,273 @@
+package buyer_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	buyerDomain "adapter/internal/domain/buyer"
+	sellerDomain "adapter/internal/domain/seller"
+	buyerPorts "adapter/internal/ports/buyer"
+	sellerPorts "adapter/internal/ports/seller"
+	"adapter/internal/testharness"
+
+	"github.com/google/uuid"
+)
+
+// recordingRefresher stands in for the broadcast service and records the refreshes it is asked for.
+type recordingRefresher struct {
+	calls [][]string
+}
+
+func (r *recordingRefresher) EnqueueRefresh(bapID, domain string, sellerIDs []string) (*buyerPorts.PermissionsJob, error) {
+	r.calls = append(r.calls, append([]string{bapID, domain}, sellerIDs...))
+	return &buyerPorts.PermissionsJob{ID: uuid.New(), BapID: bapID}, nil
+}
+
+func TestQueryBapAccessPermissions(t *testing.T) {
+	cfg := testharness.NewConfig(t, nil)
+	repo := testharness.NewPermissionsRepository(testharness.NewSellerRepository())
+	refresher := &recordingRefresher{}
+	service := buyerDomain.NewBuyerService(repo, refresher, cfg)
+
+	query := buyerPorts.BapPermissionsQueryRequest{
+		BapID:           "buyer.example",
+		Domain:          testharness.TestDomain,
+		SellerIDs:       []string{"seller-1", "seller-2", "seller-3"},
+		IncludeNoPolicy: true,
+		AutoRefresh:     true,
+	}
+	response, err := service.QueryBapAccessPermissions(query, "")
+	if err != nil {
+		t.Fatalf("QueryBapAccessPermissions: %v", err)
+	}
+	if response.BapStatus != buyerPorts.BapStatusNew || len(response.Permissions) != 3 || response.Permissions[0].Decision != "NO_POLICY" {
+		t.Errorf("unexpected response for a new BAP: %+v", response)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	reason := "manual"
+	if _, err := service.UpdateBapAccessPermissions([]sellerPorts.SellerPermissionsUpdateRequest{
+		{SellerID: "seller-1", Domain: testharness.TestDomain, BapID: "buyer.example", Decision: "ALLOWED", DecisionSource: "SELLER_API", ExpiresAt: &future, Reason: &reason},
+		{SellerID: "seller-2", Domain: testharness.TestDomain, BapID: "buyer.example", Decision: "DENIED", DecisionSource: "SELLER_API", ExpiresAt: &past, Reason: &reason},
+	}, ""); err != nil {
+		t.Fatalf("UpdateBapAccessPermissions: %v", err)
+	}
+
+	response, err = service.QueryBapAccessPermissions(query, "")
+	if err != nil {
+		t.Fatalf("QueryBapAccessPermissions: %v", err)
+	}
+	decisions := map[string]string{}
+	for _, permission := range response.Permissions {
+		decisions[permission.SellerID] = permission.Decision
+	}
+	want := map[string]string{"seller-1": "ALLOWED", "seller-2": "EXPIRED", "seller-3": "NO_POLICY"}
+	if response.BapStatus != buyerPorts.BapStatusExisting || !reflect.DeepEqual(decisions, want) {
+		t.Errorf("bap_status = %s, decisions = %v, want %v", response.BapStatus, decisions, want)
+	}
+	if !reflect.DeepEqual(response.NeedsRefresh, []string{"seller-2"}) || response.RefreshJobID == nil {
+		t.Errorf("needs_refresh = %v, refresh_job_id = %v", response.NeedsRefresh, response.RefreshJobID)
+	}
+	if !reflect.DeepEqual(refresher.calls, [][]string{{"buyer.example", testharness.TestDomain, "seller-2"}}) {
+		t.Errorf("unexpected refreshes: %v", refresher.calls)
+	}
+
+	query.OmitExpired = true
+	query.AutoRefresh = false
+	response, err = service.QueryBapAccessPermissions(query, "")
+	if err != nil {
+		t.Fatalf("QueryBapAccessPermissions: %v", err)
+	}
+	if len(response.Permissions) != 2 || len(refresher.calls) != 1 {
+		t.Errorf("omit_expired should drop seller-2 without refreshing: %+v", response.Permissions)
+	}
+}
+
+func TestUpdateBapAccessPermissionsReportsWhatWasStored(t *testing.T) {
+	cfg := testharness.NewConfig(t, nil)
+	repo := testharness.NewPermissionsRepository(testharness.NewSellerRepository())
+	service := buyerDomain.NewBuyerService(repo, nil, cfg)
+
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	if _, err := service.UpdateBapAccessPermissions([]sellerPorts.SellerPermissionsUpdateRequest{
+		{SellerID: "overridden", Domain: testharness.TestDomain, BapID: "buyer.example", Decision: "DENIED", DecisionSource: string(sellerPorts.SourceManualOverride), ExpiresAt: &future},
+		{SellerID: "lapsed", Domain: testharness.TestDomain, BapID: "buyer.example", Decision: "DENIED", DecisionSource: string(sellerPorts.SourceManualOverride), ExpiresAt: &past},
+	}, ""); err != nil {
+		t.Fatalf("UpdateBapAccessPermissions: %v", err)
+	}
+
+	results, err := service.UpdateBapAccessPermissions([]sellerPorts.SellerPermissionsUpdateRequest{
+		{SellerID: "overridden", Domain: testharness.TestDomain, BapID: "buyer.example", Decision: "ALLOWED", DecisionSource: "SELLER_API", ExpiresAt: &future},
+		{SellerID: "lapsed", Domain: testharness.TestDomain, BapID: "buyer.example", Decision: "ALLOWED", DecisionSource: "SELLER_API", ExpiresAt: &future},
+	}, "")
+	if err != nil {
+		t.Fatalf("UpdateBapAccessPermissions: %v", err)
+	}
+	// A valid manual override is kept, an expired one is replaced.
+	if results[0].Stored || !results[1].Stored {
+		t.Errorf("stored = %v, %v, want false, true", results[0].Stored, results[1].Stored)
+	}
+	if policy, _ := repo.Policy("lapsed", testharness.TestDomain, "buyer.example"); policy.Decision != sellerPorts.DecisionAllowed {
+		t.Errorf("expired manual override was not replaced: %+v", policy)
+	}
+}
+
+func TestSignedRequestsMayOnlyActForTheirOwnBap(t *testing.T) {
+	cfg := testharness.NewConfig(t, nil)
+	repo := testharness.NewPermissionsRepository(testharness.NewSellerRepository())
+	service := buyerDomain.NewBuyerService(repo, nil, cfg)
+
+	query := buyerPorts.BapPermissionsQueryRequest{BapID: "buyer.example", Domain: testharness.TestDomain, SellerIDs: []string{"seller-1"}}
+	if _, err := service.QueryBapAccessPermissions(query, "other-buyer.example"); !errors.Is(err, buyerPorts.ErrBapIDMismatch) {
+		t.Errorf("query signed by another BAP: err = %v, want ErrBapIDMismatch", err)
+	}
+	if _, err := service.QueryBapAccessPermissions(query, "buyer.example"); err != nil {
+		t.Errorf("query signed by its own BAP: %v", err)
+	}
+
+	_, err := service.UpdateBapAccessPermissions([]sellerPorts.SellerPermissionsUpdateRequest{
+		{SellerID: "seller-1", Domain: testharness.TestDomain, BapID: "other-buyer.example", Decision: "ALLOWED", DecisionSource: "SELLER_API"},
+		{SellerID: "seller-1", Domain: testharness.TestDomain, BapID: "buyer.example", Decision: "ALLOWED", DecisionSource: "SELLER_API"},
+	}, "buyer.example")
+	if !errors.Is(err, buyerPorts.ErrBapIDMismatch) {
+		t.Errorf("update for another BAP: err = %v, want ErrBapIDMismatch", err)
+	}
+	if _, ok := repo.Policy("seller-1", testharness.TestDomain, "buyer.example"); ok {
+		t.Error("a rejected update must not store any policy")
+	}
+}
+
+func TestQueryBapAccessPermissionsRegistryModes(t *testing.T) {
+	registry := testharness.NewRegistry(t)
+	cfg := testharness.NewConfig(t, registry)
+	cfg.BapRegistryMode = buyerPorts.BapRegistryModeStrict
+	repo := testharness.NewPermissionsRepository(testharness.NewSellerRepository())
+	service := buyerDomain.NewBuyerService(repo, nil, cfg)
+
+	query := buyerPorts.BapPermissionsQueryRequest{BapID: "buyer.example", Domain: testharness.TestDomain, SellerIDs: []string{"seller-1"}}
+	if _, err := service.QueryBapAccessPermissions(query, ""); !errors.Is(err, buyerPorts.ErrBapNotRegistered) {
+		t.Fatalf("err = %v, want ErrBapNotRegistered", err)
+	}
+	if _, err := repo.FindBapByID("buyer.example"); err == nil {
+		t.Error("a rejected BAP should not be recorded")
+	}
+
+	expired := testharness.RegistryEntry{
+		SubscriberID:  "expired.example",
+		SubscriberURL: "https://expired.example/ondc",
+		Type:          "BAP",
+		UkID:          "expired-key",
+		Domain:        testharness.TestDomain,
+		Country:       "IND",
+		Status:        "SUBSCRIBED",
+		ValidFrom:     "2020-01-01T00:00:00Z",
+		ValidUntil:    "2021-01-01T00:00:00Z",
+	}
+	subscribed := expired
+	subscribed.SubscriberID = "buyer.example"
+	subscribed.ValidUntil = "2099-01-01"
+	registry.SetEntries(subscribed, expired)
+	sync := sellerDomain.NewSellerService(testharness.NewSellerRepository(), repo, cfg)
+	if _, err := sync.SyncRegistry(context.Background(), sellerPorts.SellerRegistrySyncRequest{Domains: []string{testharness.TestDomain}, Types: []string{"BAP"}}, sellerPorts.RegistrySyncTriggerAPI); err != nil {
+		t.Fatalf("SyncRegistry: %v", err)
+	}
+
+	response, err := service.QueryBapAccessPermissions(query, "")
+	if err != nil {
+		t.Fatalf("a subscribed BAP should be accepted in strict mode: %v", err)
+	}
+	// Being synced from the registry does not count as having called the adapter.
+	if response.BapStatus != buyerPorts.BapStatusNew {
+		t.Errorf("first bap_status = %s, want %s", response.BapStatus, buyerPorts.BapStatusNew)
+	}
+	if bap, err := repo.FindBapByID("buyer.example"); err != nil || bap.FirstSeenAt == nil || bap.LastSeenAt == nil {
+		t.Errorf("queried BAP = %+v, %v, want first_seen_at and last_seen_at set", bap, err)
+	}
+	if response, err = service.QueryBapAccessPermissions(query, ""); err != nil || response.BapStatus != buyerPorts.BapStatusExisting {
+		t.Errorf("second query = %+v, %v, want %s", response, err, buyerPorts.BapStatusExisting)
+	}
+
+	query.BapID = "expired.example"
+	if _, err := service.QueryBapAccessPermissions(query, ""); !errors.Is(err, buyerPorts.ErrBapNotRegistered) {
+		t.Errorf("err = %v, want ErrBapNotRegistered for an expired BAP", err)
+	}
+
+	cfg.BapRegistryMode = buyerPorts.BapRegistryModeReport
+	response, err = service.QueryBapAccessPermissions(query, "")
+	if err != nil {
+		t.Fatalf("report mode should not reject: %v", err)
+	}
+	if response.BapStatus != buyerPorts.BapStatusExpired {
+		t.Errorf("bap_status = %s, want %s", response.BapStatus, buyerPorts.BapStatusExpired)
+	}
+}
+
+func TestRefreshExpiringPolicies(t *testing.T) {
+	cfg := testharness.NewConfig(t, nil)
+	cfg.PolicyRefreshMaxPerSeller = 1
+	sellers := testharness.NewSellerRepository()
+	repo := testharness.NewPermissionsRepository(sellers)
+	refresher := &recordingRefresher{}
+	service := buyerDomain.NewBuyerService(repo, refresher, cfg)
+
+	now := time.Now()
+	var seeded []sellerPorts.Seller
+	for _, id := range []string{"seller-1", "seller-2", "seller-3", "gone"} {
+		seeded = append(seeded, sellerPorts.Seller{SellerID: id, Domain: testharness.TestDomain, Active: id != "gone"})
+	}
+	if err := sellers.InsertSellers(seeded); err != nil {
+		t.Fatalf("InsertSellers: %v", err)
+	}
+	idleSince := now.Add(-30 * 24 * time.Hour)
+	if err := repo.UpsertBaps(map[string]buyerPorts.Bap{
+		"bap-a": {BapID: "bap-a", FirstSeenAt: &now, LastSeenAt: &now},
+		"bap-b": {BapID: "bap-b", FirstSeenAt: &now, LastSeenAt: &now},
+		"idle":  {BapID: "idle", FirstSeenAt: &idleSince, LastSeenAt: &idleSince},
+	}); err != nil {
+		t.Fatalf("UpsertBaps: %v", err)
+	}
+	soon := now.Add(10 * time.Minute)
+	// bap-b expires after bap-a, so the per-seller cap drops seller-1 from bap-b's probe.
+	soonAfter := now.Add(20 * time.Minute)
+	later := now.Add(24 * time.Hour)
+	longAgo := now.Add(-7 * 24 * time.Hour)
+	policy := func(sellerID, bapID string, expiresAt *time.Time) buyerPorts.BapAccessPolicy {
+		return buyerPorts.BapAccessPolicy{SellerID: sellerID, Domain: testharness.TestDomain, BapID: bapID, Decision: sellerPorts.DecisionAllowed, DecisionSource: sellerPorts.SourceSellerOnSearch, DecidedAt: now, ExpiresAt: expiresAt}
+	}
+	if _, err := repo.UpsertBapAccessPolicies([]buyerPorts.BapAccessPolicy{
+		policy("seller-1", "bap-a", &soon),
+		policy("seller-2", "bap-a", &soon),
+		policy("seller-1", "bap-b", &soonAfter),
+		policy("seller-3", "bap-b", &later),
+		policy("seller-3", "idle", &soon),
+		policy("gone", "bap-a", &soon),
+		policy("seller-3", "bap-a", &longAgo),
+	}); err != nil {
+		t.Fatalf("UpsertBapAccessPolicies: %v", err)
+	}
+
+	summary, err := service.RefreshExpiringPolicies(context.Background())
+	if err != nil {
+		t.Fatalf("RefreshExpiringPolicies: %v", err)
+	}
+	if summary.Candidates != 3 || summary.Probes != 2 || summary.SkippedSellerLimit != 1 || len(summary.JobIDs) != 1 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if len(refresher.calls) != 1 || refresher.calls[0][0] != "bap-a" || len(refresher.calls[0]) != 4 {
+		t.Errorf("expected one batched probe for bap-a, got %v", refresher.calls)
+	}
+
+	// Probed policies back off, even though no on_search has settled them.
+	summary, err = service.RefreshExpiringPolicies(context.Background())
+	if err != nil {
+		t.Fatalf("RefreshExpiringPolicies: %v", err)
+	}
+	if summary.Candidates != 1 || summary.Probes != 1 || len(refresher.calls) != 2 || refresher.calls[1][0] != "bap-b" {
+		t.Errorf("second run should only probe seller-1 for bap-b, got %+v and %v", summary, refresher.calls)
+	}
+}
@@ -0,0 +1,136 @@
+package seller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	buyerPorts "adapter/internal/ports/buyer"
+	sellerPorts "adapter/internal/ports/seller"
+	"adapter/internal/shared/log"
+)
+
+var ErrUnsupportedSubscriberType = errors.New("unsupported subscriber type")
+
+// BapRegistryStore persists the BAPs found in the registry into the baps table
+type BapRegistryStore interface {
+	UpsertRegistryBaps(ctx context.Context, baps []buyerPorts.Bap) error
+}
+
+// syncTypes resolves the subscriber types a sync covers, defaulting to REGISTRY_SUBSCRIBER_TYPES.
+// Only BPPs and BAPs have a table to sync into.
+func (s *SellerService) syncTypes(requested []string) (map[string]bool, error) {
+	if len(requested) == 0 {
+		requested = s.config.RegistrySubscriberTypes
+	}
+	types := make(map[string]bool)
+	for _, t := range requested {
+		t = strings.ToUpper(strings.TrimSpace(t))
+		switch t {
+		case SubscriberTypeBPP:
+		case SubscriberTypeBAP:
+			if s.bapStore == nil {
+				return nil, fmt.Errorf("%w: %s sync is not configured", ErrUnsupportedSubscriberType, t)
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedSubscriberType, t)
+		}
+		types[t] = true
+	}
+	if len(types) == 0 {
+		types[SubscriberTypeBPP] = true
+	}
+	return types, nil
+}
+
+// fetchBapDomain reads the BAPs the registry lists for one domain, one entry per BAP. They are
+// returned rather than stored so that SyncRegistry can store a single result per BAP.
+func (s *SellerService) fetchBapDomain(ctx context.Context, req sellerPorts.SellerRegistrySyncRequest, domain string, now time.Time) (sellerPorts.BapDomainSyncSummary, []buyerPorts.Bap) {
+	summary := sellerPorts.BapDomainSyncSummary{Domain: domain, Status: sellerPorts.SyncStatusOK}
+
+	subscribers, _, err := s.FetchSubscribers(ctx, s.lookupParams(req, SubscriberTypeBAP, domain))
+	if err != nil {
+		log.Errorf(ctx, err, "Failed to fetch BAPs from registry for domain %s", domain)
+		message := fmt.Sprintf("fetch from registry: %v", err)
+		summary.Error = &message
+		summary.Status = sellerPorts.SyncStatusFailed
+		return summary, nil
+	}
+	summary.TotalInRegistry = len(subscribers)
+
+	var baps bapSet
+	for _, sub := range subscribers {
+		if sub.SubscriberID == "" {
+			summary.MalformedRecords++
+			continue
+		}
+		validity, err := subscriberValidity(sub)
+		if err != nil {
+			log.Warnf(ctx, "Registry record for BAP %s in domain %s is malformed: %v", sub.SubscriberID, domain, err)
+			summary.MalformedRecords++
+		}
+		baps.add(buyerPorts.Bap{
+			BapID:         sub.SubscriberID,
+			LastSeenInReg: &now,
+			Status:        sub.Status,
+			SubscriberURL: sub.SubscriberURL,
+			ValidFrom:     validity.From,
+			ValidUntil:    validity.Until,
+			// The stored bounds are kept in place of malformed ones.
+			KeepValidFrom:  validity.FromMalformed,
+			KeepValidUntil: validity.UntilMalformed,
+			UkID:           sub.UkID,
+			SigningKey:     sub.SigningKey,
+			EncryptionKey:  sub.EncryptionKey,
+		}, now)
+	}
+	summary.Upserted = len(baps.baps)
+	return summary, baps.baps
+}
+
+// storeBaps upserts the BAPs found across all synced domains, one row per BAP, so that the stored
+// status does not depend on the order the domains were synced in.
+func (s *SellerService) storeBaps(ctx context.Context, found []buyerPorts.Bap, summaries []sellerPorts.BapDomainSyncSummary, now time.Time) {
+	var baps bapSet
+	for _, bap := range found {
+		baps.add(bap, now)
+	}
+	if err := s.bapStore.UpsertRegistryBaps(ctx, baps.baps); err != nil {
+		log.Errorf(ctx, err, "Failed to upsert %d registry BAPs", len(baps.baps))
+		message := err.Error()
+		for i := range summaries {
+			if summaries[i].Status == sellerPorts.SyncStatusFailed {
+				continue
+			}
+			summaries[i].Error = &message
+			summaries[i].Status = sellerPorts.SyncStatusFailed
+			summaries[i].Upserted = 0
+		}
+	}
+}
+
+// bapSet keeps one registry entry per BAP: a currently valid SUBSCRIBED entry wins over any
+// other, and a SUBSCRIBED one over the rest.
+type bapSet struct {
+	index map[string]int
+	baps  []buyerPorts.Bap
+}
+
+func (set *bapSet) add(bap buyerPorts.Bap, now time.Time) {
+	if set.index == nil {
+		set.index = make(map[string]int)
+	}
+	i, exists := set.index[bap.BapID]
+	if !exists {
+		set.index[bap.BapID] = len(set.baps)
+		set.baps = append(set.baps, bap)
+		return
+	}
+	currentValid, candidateValid := set.baps[i].RegistryStatus(now, 0) == "", bap.RegistryStatus(now, 0) == ""
+	if candidateValid && !currentValid ||
+		candidateValid == currentValid && bap.Status == sellerPorts.SubscriberStatusSubscribed && set.baps[i].Status != sellerPorts.SubscriberStatusSubscribed {
+		set.baps[i] = bap
+	}
+}
@@ -0,0 +1,92 @@
+package seller
+
+import (
+	"encoding/json"
+	"time"
+
+	sellerPorts "adapter/internal/ports/seller"
+)
+
+// Fields tracked in seller_changes
+const (
+	changeFieldCreated       = "created"
+	changeFieldActive        = "active"
+	changeFieldStatus        = "status"
+	changeFieldSubscriberURL = "subscriber_url"
+	changeFieldType          = "type"
+	changeFieldBrID          = "br_id"
+	changeFieldUkID          = "ukId"
+	changeFieldCountry       = "country"
+	changeFieldCity          = "city"
+	changeFieldValidFrom     = "valid_from"
+	changeFieldValidUntil    = "valid_until"
+	changeFieldSigningKey    = "signing_public_key"
+	changeFieldEncryptionKey = "encr_public_key"
+	changeFieldRegistryRaw   = "registry_raw"
+)
+
+// diffSeller compares a stored seller with the one built from the registry and returns the
+// changes between them. An empty result means the stored row is already up to date.
+func diffSeller(stored, incoming sellerPorts.Seller) []sellerPorts.SellerChange {
+	var changes []sellerPorts.SellerChange
+	add := func(field, oldValue, newValue string) {
+		if oldValue == newValue {
+			return
+		}
+		changes = append(changes, sellerPorts.SellerChange{
+			SellerID: incoming.SellerID,
+			Domain:   incoming.Domain,
+			Field:    field,
+			OldValue: &oldValue,
+			NewValue: &newValue,
+		})
+	}
+
+	if !stored.Active {
+		add(changeFieldActive, "false", "true")
+	}
+	add(changeFieldStatus, stored.Status, incoming.Status)
+	add(changeFieldSubscriberURL, stored.SubscriberURL, incoming.SubscriberURL)
+	add(changeFieldType, stored.Type, incoming.Type)
+	add(changeFieldBrID, stored.BrID, incoming.BrID)
+	add(changeFieldUkID, stored.UkID, incoming.UkID)
+	add(changeFieldSigningKey, stored.SigningKey, incoming.SigningKey)
+	add(changeFieldEncryptionKey, stored.EncryptionKey, incoming.EncryptionKey)
+	add(changeFieldCountry, stored.Country, incoming.Country)
+	add(changeFieldCity, stored.City, incoming.City)
+	add(changeFieldValidFrom, formatChangeTime(stored.ValidFrom), formatChangeTime(incoming.ValidFrom))
+	add(changeFieldValidUntil, formatChangeTime(stored.ValidUntil), formatChangeTime(incoming.ValidUntil))
+
+	// jsonb does not preserve formatting, so registry_raw is compared decoded. A snapshot that
+	// does not decode cannot be compared and always counts as changed.
+	var storedSub, incomingSub Subscriber
+	storedOK := stored.RegistryRaw != "" && json.Unmarshal([]byte(stored.RegistryRaw), &storedSub) == nil
+	incomingOK := json.Unmarshal([]byte(incoming.RegistryRaw), &incomingSub) == nil
+	if len(changes) == 0 && (!storedOK || !incomingOK || storedSub != incomingSub) {
+		// Something untracked changed; store the new snapshot without logging its content.
+		changes = append(changes, sellerPorts.SellerChange{
+			SellerID: incoming.SellerID,
+			Domain:   incoming.Domain,
+			Field:    changeFieldRegistryRaw,
+		})
+	}
+	return changes
+}
+
+func newSellerChange(seller sellerPorts.Seller) sellerPorts.SellerChange {
+	return sellerPorts.SellerChange{SellerID: seller.SellerID, Domain: seller.Domain, Field: changeFieldCreated}
+}
+
+func deactivatedSellerChange(sellerID, domain string) sellerPorts.SellerChange {
+	oldValue, newValue := "true", "false"
+	return sellerPorts.SellerChange{SellerID: sellerID, Domain: domain, Field: changeFieldActive, OldValue: &oldValue, NewValue: &newValue}
+}
+
+// formatChangeTime renders a missing validity as "". Rows synced before validity became
+// nullable may still hold the zero time, which is treated the same way.
+func formatChangeTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
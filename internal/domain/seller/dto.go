@@ -1,6 +1,7 @@
 package seller
 
 import (
+	"adapter/internal/config"
 	sellerPorts "adapter/internal/ports/seller"
 	"adapter/internal/shared/crypto"
 
@@ -8,24 +9,30 @@ import (
 )
 
 type SellerService struct {
-	repo         sellerPorts.SellerRepository
-	client       *resty.Client
-	crypto       *crypto.ONDCCrypto
-	domains      []string
-	registryURL  string
-	privateKey   string
-	subscriberID string
-	uniqueKeyID  string
+	repo        sellerPorts.SellerRepository
+	bapStore    BapRegistryStore
+	client      *resty.Client
+	signer      *crypto.RequestSigner
+	domains     []string
+	registryURL string
+	config      *config.Config
 }
 
 type ONDCLookupRequest struct {
-	Country string `json:"country"`
-	Type    string `json:"type"`
-	Domain  string `json:"domain"`
+	Country      string `json:"country,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Domain       string `json:"domain,omitempty"`
+	City         string `json:"city,omitempty"`
+	SubscriberID string `json:"subscriber_id,omitempty"`
+	UkID         string `json:"ukId,omitempty"`
+	Page         int    `json:"page,omitempty"`
+	Limit        int    `json:"limit,omitempty"`
 }
 
 type Subscriber struct {
 	SubscriberID  string `json:"subscriber_id"`
+	SubscriberURL string `json:"subscriber_url"`
+	Type          string `json:"type"`
 	UkID          string `json:"ukId"`
 	BrID          string `json:"br_id"`
 	Domain        string `json:"domain"`
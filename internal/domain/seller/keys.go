@@ -0,0 +1,120 @@
+package seller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"adapter/internal/shared/caching"
+	"adapter/internal/shared/log"
+)
+
+var ErrSigningKeyNotFound = errors.New("signing public key not found for subscriber")
+
+// SigningKeyResolver resolves the signing public key of an ONDC subscriber, preferring the
+// registry data already synced into the sellers table and falling back to a registry lookup.
+// Keys that cannot be resolved are remembered for missTTL, so that unauthenticated traffic with
+// made-up keyIds is not amplified into registry lookups.
+type SigningKeyResolver struct {
+	service  *SellerService
+	cache    caching.CacheService
+	cacheTTL time.Duration
+	missTTL  time.Duration
+}
+
+func NewSigningKeyResolver(service *SellerService, cache caching.CacheService, cacheTTL, missTTL time.Duration) *SigningKeyResolver {
+	return &SigningKeyResolver{
+		service:  service,
+		cache:    cache,
+		cacheTTL: cacheTTL,
+		missTTL:  missTTL,
+	}
+}
+
+// ResolveSigningKey returns the base64 encoded ed25519 public key for the subscriber/ukId pair
+func (r *SigningKeyResolver) ResolveSigningKey(ctx context.Context, subscriberID, ukID string) (string, error) {
+	cacheKey := fmt.Sprintf("signing_key:%s|%s", subscriberID, ukID)
+	missKey := fmt.Sprintf("signing_key_miss:%s|%s", subscriberID, ukID)
+	if r.cache != nil {
+		var cached string
+		if err := r.cache.Get(ctx, cacheKey, &cached); err == nil && cached != "" {
+			return cached, nil
+		}
+		var missed bool
+		if err := r.cache.Get(ctx, missKey, &missed); err == nil && missed {
+			return "", ErrSigningKeyNotFound
+		}
+	}
+
+	key, err := r.lookupStoredKey(subscriberID, ukID)
+	if err != nil {
+		log.Errorf(ctx, err, "Failed to read stored registry data for subscriber %s", subscriberID)
+	}
+
+	if key == "" {
+		log.Infof(ctx, "No stored signing key for subscriber %s (ukId %s), falling back to registry lookup", subscriberID, ukID)
+		subscribers, err := r.service.LookupSubscriber(subscriberID, ukID)
+		if err != nil {
+			r.cacheMiss(ctx, missKey, subscriberID)
+			return "", fmt.Errorf("registry lookup failed for subscriber %s: %w", subscriberID, err)
+		}
+		key = matchSigningKey(subscribers, subscriberID, ukID)
+	}
+
+	if key == "" {
+		r.cacheMiss(ctx, missKey, subscriberID)
+		return "", ErrSigningKeyNotFound
+	}
+
+	if r.cache != nil {
+		if err := r.cache.Set(ctx, cacheKey, key, r.cacheTTL); err != nil {
+			log.Errorf(ctx, err, "Failed to cache signing key for subscriber %s", subscriberID)
+		}
+	}
+	return key, nil
+}
+
+func (r *SigningKeyResolver) cacheMiss(ctx context.Context, missKey, subscriberID string) {
+	if r.cache == nil || r.missTTL <= 0 {
+		return
+	}
+	if err := r.cache.Set(ctx, missKey, true, r.missTTL); err != nil {
+		log.Errorf(ctx, err, "Failed to cache signing key miss for subscriber %s", subscriberID)
+	}
+}
+
+func (r *SigningKeyResolver) lookupStoredKey(subscriberID, ukID string) (string, error) {
+	sellers, err := r.service.repo.GetSellersByFilters(map[string]interface{}{"seller_id": subscriberID})
+	if err != nil {
+		return "", err
+	}
+
+	var subscribers ONDCLookupResponse
+	for _, seller := range sellers {
+		if seller.UkID != "" {
+			subscribers = append(subscribers, Subscriber{SubscriberID: seller.SellerID, UkID: seller.UkID, SigningKey: seller.SigningKey})
+			continue
+		}
+		// Rows synced before the key columns existed only carry the keys in registry_raw.
+		if seller.RegistryRaw == "" {
+			continue
+		}
+		var sub Subscriber
+		if err := json.Unmarshal([]byte(seller.RegistryRaw), &sub); err != nil {
+			continue
+		}
+		subscribers = append(subscribers, sub)
+	}
+	return matchSigningKey(subscribers, subscriberID, ukID), nil
+}
+
+func matchSigningKey(subscribers ONDCLookupResponse, subscriberID, ukID string) string {
+	for _, sub := range subscribers {
+		if sub.SubscriberID == subscriberID && sub.UkID == ukID && sub.SigningKey != "" {
+			return sub.SigningKey
+		}
+	}
+	return ""
+}
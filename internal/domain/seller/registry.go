@@ -0,0 +1,181 @@
+package seller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	sellerPorts "adapter/internal/ports/seller"
+	"adapter/internal/shared/crypto"
+	"adapter/internal/shared/log"
+)
+
+const (
+	SubscriberTypeBPP = "BPP"
+	SubscriberTypeBAP = "BAP"
+	SubscriberTypeBG  = "BG"
+)
+
+// v1 registries serve /lookup unauthenticated; v2 requires a signed request.
+const registryLookupV1 = "v1"
+
+// LookupParams selects the registry subscribers to fetch. Each country is looked up separately.
+type LookupParams struct {
+	Type      string
+	Domain    string
+	City      string
+	Countries []string
+}
+
+// lookupParams builds the lookup for a subscriber type and domain, letting the sync request
+// override the configured countries and city.
+func (s *SellerService) lookupParams(req sellerPorts.SellerRegistrySyncRequest, subscriberType, domain string) LookupParams {
+	params := LookupParams{
+		Type:      subscriberType,
+		Domain:    domain,
+		City:      s.config.RegistryCity,
+		Countries: s.config.RegistryCountries,
+	}
+	if len(req.Countries) > 0 {
+		params.Countries = req.Countries
+	}
+	if req.City != "" {
+		params.City = req.City
+	}
+	return params
+}
+
+func (s *SellerService) FetchSellersFromRegistry(domain string) (ONDCLookupResponse, error) {
+	subscribers, _, err := s.FetchSubscribers(context.Background(), s.lookupParams(sellerPorts.SellerRegistrySyncRequest{}, SubscriberTypeBPP, domain))
+	return subscribers, err
+}
+
+// FetchSubscribers looks up the subscribers matching params in every country, following pages
+// when REGISTRY_PAGE_SIZE is set. Entries repeated across pages are returned once, and a page
+// with nothing new ends the lookup, as from a registry that ignores paging. complete is false
+// when a lookup was cut off at REGISTRY_MAX_PAGES. Cancelling ctx aborts the lookup.
+func (s *SellerService) FetchSubscribers(ctx context.Context, params LookupParams) (subscribers ONDCLookupResponse, complete bool, err error) {
+	countries := params.Countries
+	if len(countries) == 0 {
+		countries = []string{""}
+	}
+	pageSize := s.config.RegistryPageSize
+
+	complete = true
+	seen := make(map[string]bool)
+	for _, country := range countries {
+		for page := 1; ; page++ {
+			req := ONDCLookupRequest{Country: country, Type: params.Type, Domain: params.Domain, City: params.City}
+			if pageSize > 0 {
+				req.Page = page
+				req.Limit = pageSize
+			}
+
+			batch, err := s.lookup(ctx, req)
+			if err != nil {
+				return nil, false, fmt.Errorf("lookup of %s subscribers in %s (page %d): %w", params.Type, country, page, err)
+			}
+			added := 0
+			for _, sub := range batch {
+				key := strings.Join([]string{sub.SubscriberID, sub.UkID, sub.Domain, sub.Type}, "|")
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				subscribers = append(subscribers, sub)
+				added++
+			}
+
+			if pageSize <= 0 || len(batch) < pageSize {
+				break
+			}
+			if added == 0 {
+				log.Warnf(ctx, "Page %d of the %s lookup for domain %s in %s repeated earlier entries; stopping", page, params.Type, params.Domain, country)
+				break
+			}
+			if page >= s.config.RegistryMaxPages {
+				log.Warnf(ctx, "Stopping %s lookup for domain %s in %s after %d pages", params.Type, params.Domain, country, page)
+				complete = false
+				break
+			}
+		}
+	}
+	return subscribers, complete, nil
+}
+
+// inScope reports whether a stored seller falls under the lookup's country and city filters,
+// so that its absence from the lookup means it left the registry. A lookup without filters
+// covers every seller of the domain.
+func (params LookupParams) inScope(seller sellerPorts.Seller) bool {
+	if params.City != "" && !strings.EqualFold(seller.City, params.City) {
+		return false
+	}
+	if len(params.Countries) == 0 {
+		return true
+	}
+	for _, country := range params.Countries {
+		if strings.EqualFold(seller.Country, country) {
+			return true
+		}
+	}
+	return false
+}
+
+// LookupSubscriber fetches the registry entries for a single subscriber and unique key id
+func (s *SellerService) LookupSubscriber(subscriberID, ukID string) (ONDCLookupResponse, error) {
+	return s.lookup(context.Background(), ONDCLookupRequest{SubscriberID: subscriberID, UkID: ukID})
+}
+
+func (s *SellerService) lookup(ctx context.Context, reqBody ONDCLookupRequest) (ONDCLookupResponse, error) {
+	payload, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, err
+	}
+	request := s.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(payload)
+	if s.config.RegistryLookupVersion != registryLookupV1 {
+		authHeader, err := s.signer.Sign(payload)
+		if err != nil {
+			return nil, err
+		}
+		request.SetHeader(crypto.HeaderAuthorization, authHeader)
+	}
+
+	resp, err := request.Post(s.registryURL)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode(), resp.String())
+	}
+	return decodeLookupResponse(resp.Body())
+}
+
+// decodeLookupResponse accepts the plain array returned by /lookup as well as the wrapped
+// {"subscribers": [...]} or {"data": [...]} bodies of paginated registries.
+func decodeLookupResponse(body []byte) (ONDCLookupResponse, error) {
+	trimmed := bytes.TrimSpace(body)
+	var response ONDCLookupResponse
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &response); err != nil {
+			return nil, fmt.Errorf("failed to decode lookup response: %w", err)
+		}
+		return response, nil
+	}
+
+	var wrapped struct {
+		Subscribers ONDCLookupResponse `json:"subscribers"`
+		Data        ONDCLookupResponse `json:"data"`
+	}
+	if err := json.Unmarshal(trimmed, &wrapped); err != nil {
+		return nil, fmt.Errorf("failed to decode lookup response: %w", err)
+	}
+	if wrapped.Subscribers != nil {
+		return wrapped.Subscribers, nil
+	}
+	return wrapped.Data, nil
+}
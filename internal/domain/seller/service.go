@@ -8,29 +8,36 @@ import (
 	"time"
 
 	"adapter/internal/config"
+	buyerPorts "adapter/internal/ports/buyer"
 	sellerPorts "adapter/internal/ports/seller"
 	"adapter/internal/shared/crypto"
 	"adapter/internal/shared/log"
 
 	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-func NewSellerService(repo sellerPorts.SellerRepository, cfg *config.Config) *SellerService {
+// NewSellerService builds the seller service. bapStore may be nil when BAPs are not synced.
+func NewSellerService(repo sellerPorts.SellerRepository, bapStore BapRegistryStore, cfg *config.Config) *SellerService {
 	client := resty.New()
 	client.SetTimeout(30 * time.Second)
 	client.SetRetryCount(3)
 	client.SetRetryWaitTime(5 * time.Second)
 
 	return &SellerService{
-		repo:         repo,
-		client:       client,
-		crypto:       crypto.NewONDCCrypto(),
-		domains:      cfg.Domains,
-		registryURL:  cfg.RegistryURL,
-		privateKey:   cfg.PrivateKey,
-		subscriberID: cfg.SubscriberID,
-		uniqueKeyID:  cfg.UniqueKeyID,
+		repo:     repo,
+		bapStore: bapStore,
+		client:   client,
+		signer: crypto.NewRequestSigner(crypto.SignerConfig{
+			SubscriberID: cfg.SubscriberID,
+			UniqueKeyID:  cfg.UniqueKeyID,
+			PrivateKey:   cfg.PrivateKey,
+			TTL:          cfg.RequestSignatureTTL,
+		}),
+		domains:     cfg.Domains,
+		registryURL: cfg.RegistryURL,
+		config:      cfg,
 	}
 }
 
@@ -107,140 +114,372 @@ func (s *SellerService) GetSyncStatus(sellerID, domain string) (*sellerPorts.Sel
 	}, nil
 }
 
-func (s *SellerService) SyncRegistry(req sellerPorts.SellerRegistrySyncRequest) (*sellerPorts.SellerRegistrySyncResponse, error) {
+// SyncRegistry reconciles the sellers table, and the baps table when BAPs are synced, with the
+// registry for each requested domain and records the run in registry_sync_runs. trigger says
+// whether cron or the API started it.
+// Cancelling ctx stops the sync before the next domain and aborts its registry lookups and
+// writes; the run is still recorded, as failed for the domains it did not reach.
+func (s *SellerService) SyncRegistry(ctx context.Context, req sellerPorts.SellerRegistrySyncRequest, trigger string) (*sellerPorts.SellerRegistrySyncResponse, error) {
+	types, err := s.syncTypes(req.Types)
+	if err != nil {
+		return nil, err
+	}
+
 	runAt := time.Now()
 	response := &sellerPorts.SellerRegistrySyncResponse{
 		RunAt:   runAt.Format(time.RFC3339),
 		Domains: []sellerPorts.SellerDomainSyncSummary{},
 	}
 
-	for _, domain := range req.Domains {
-		summary := sellerPorts.SellerDomainSyncSummary{Domain: domain}
+	// A run that cannot be recorded should not stop the sync itself.
+	run := &sellerPorts.RegistrySyncRun{Trigger: trigger, StartedAt: runAt}
+	if err := s.repo.CreateRegistrySyncRun(run); err != nil {
+		log.Errorf(ctx, err, "Failed to record registry sync run")
+		run = nil
+	} else {
+		response.RunID = run.ID.String()
+	}
 
-		registrySellers, err := s.FetchSellersFromRegistry(domain)
-		if err != nil {
-			log.Error(context.Background(), err, fmt.Sprintf("Failed to fetch sellers from registry for domain %s", domain))
-			continue
+	var statuses []string
+	var baps []buyerPorts.Bap
+	for _, domain := range req.Domains {
+		if ctx.Err() != nil {
+			break
 		}
-		summary.TotalSellersInRegistry = len(registrySellers)
-
-		dbSellers, err := s.repo.GetSellersByFilters(map[string]interface{}{"domain": domain, "active": true})
-		if err != nil {
-			log.Error(context.Background(), err, fmt.Sprintf("Failed to fetch sellers from DB for domain %s", domain))
-			continue
+		if types[SubscriberTypeBPP] {
+			summary := s.syncSellerDomain(ctx, req, domain, run)
+			response.Domains = append(response.Domains, summary)
+			response.MalformedRecords += summary.MalformedRecords
+			statuses = append(statuses, summary.Status)
+			if run != nil {
+				run.Domains = append(run.Domains, sellerPorts.RegistrySyncRunDomain{
+					Domain:                 domain,
+					Status:                 summary.Status,
+					NewSellers:             summary.NewSellers,
+					UpdatedSellers:         summary.UpdatedSellers,
+					ReactivatedSellers:     summary.ReactivatedSellers,
+					UnchangedSellers:       summary.UnchangedSellers,
+					InvalidURLSellers:      summary.InvalidURLSellers,
+					DeactivatedSellers:     summary.DeactivatedSellers,
+					TotalSellersInRegistry: summary.TotalSellersInRegistry,
+					MalformedRecords:       summary.MalformedRecords,
+					Error:                  summary.Error,
+				})
+			}
+		}
+		if types[SubscriberTypeBAP] {
+			summary, found := s.fetchBapDomain(ctx, req, domain, runAt)
+			response.Baps = append(response.Baps, summary)
+			response.MalformedRecords += summary.MalformedRecords
+			baps = append(baps, found...)
 		}
+	}
+	if types[SubscriberTypeBAP] && ctx.Err() == nil {
+		s.storeBaps(ctx, baps, response.Baps, runAt)
+		for _, summary := range response.Baps {
+			statuses = append(statuses, summary.Status)
+		}
+	}
 
-		registrySellerMap := make(map[string]sellerPorts.Seller)
-		now := time.Now()
-		for _, sub := range registrySellers {
-			validFrom, _ := time.Parse(time.RFC3339, sub.ValidFrom)
-			validUntil, _ := time.Parse(time.RFC3339, sub.ValidUntil)
-			raw, _ := json.Marshal(sub)
+	cancelled := ctx.Err()
+	if cancelled != nil {
+		statuses = append(statuses, sellerPorts.SyncStatusFailed)
+	}
+	response.Status = overallSyncStatus(statuses)
+	// The run is recorded without ctx so that a cancelled run is not left unfinished.
+	if run != nil {
+		finishedAt := time.Now()
+		run.FinishedAt = &finishedAt
+		run.Status = response.Status
+		run.MalformedRecords = response.MalformedRecords
+		if err := s.repo.FinishRegistrySyncRun(run); err != nil {
+			log.Errorf(ctx, err, "Failed to record results of registry sync run %s", run.ID)
+		}
+	}
+	if cancelled != nil {
+		return nil, fmt.Errorf("registry sync cancelled: %w", cancelled)
+	}
+	return response, nil
+}
 
-			seller := sellerPorts.Seller{
-				SellerID: sub.SubscriberID, Domain: sub.Domain,
-				Status: sub.Status, Type: "BPP", SubscriberURL: sub.SubscriberID,
-				Country: sub.Country, City: sub.City, ValidFrom: validFrom, ValidUntil: validUntil,
-				Active: true, LastSeenInReg: now, RegistryRaw: string(raw),
+// syncSellerDomain reconciles the sellers of one domain with the BPPs listed in the registry
+func (s *SellerService) syncSellerDomain(ctx context.Context, req sellerPorts.SellerRegistrySyncRequest, domain string, run *sellerPorts.RegistrySyncRun) sellerPorts.SellerDomainSyncSummary {
+	summary := sellerPorts.SellerDomainSyncSummary{Domain: domain, Status: sellerPorts.SyncStatusOK}
+	var domainErrors []string
+	finishDomain := func(status string) sellerPorts.SellerDomainSyncSummary {
+		if len(domainErrors) > 0 {
+			message := strings.Join(domainErrors, "; ")
+			summary.Error = &message
+			if summary.Status == sellerPorts.SyncStatusOK {
+				summary.Status = sellerPorts.SyncStatusPartial
 			}
-			registrySellerMap[seller.SellerID] = seller
 		}
+		if status != "" {
+			summary.Status = status
+		}
+		return summary
+	}
+
+	params := s.lookupParams(req, SubscriberTypeBPP, domain)
+	registrySellers, complete, err := s.FetchSubscribers(ctx, params)
+	if err != nil {
+		log.Error(ctx, err, fmt.Sprintf("Failed to fetch sellers from registry for domain %s", domain))
+		domainErrors = append(domainErrors, fmt.Sprintf("fetch from registry: %v", err))
+		return finishDomain(sellerPorts.SyncStatusFailed)
+	}
+	summary.TotalSellersInRegistry = len(registrySellers)
+
+	// Inactive sellers are read too so that one returning to the registry is reactivated
+	// rather than inserted again.
+	dbSellers, err := s.repo.GetSellersByFilters(map[string]interface{}{"domain": domain})
+	if err != nil {
+		log.Error(ctx, err, fmt.Sprintf("Failed to fetch sellers from DB for domain %s", domain))
+		domainErrors = append(domainErrors, fmt.Sprintf("read sellers: %v", err))
+		return finishDomain(sellerPorts.SyncStatusFailed)
+	}
+
+	dbSellerMap := make(map[string]sellerPorts.Seller)
+	for _, seller := range dbSellers {
+		dbSellerMap[seller.SellerID] = seller
+	}
 
-		dbSellerMap := make(map[string]sellerPorts.Seller)
-		for _, seller := range dbSellers {
-			dbSellerMap[seller.SellerID] = seller
+	registrySellerMap := make(map[string]sellerPorts.Seller)
+	now := time.Now()
+	for _, sub := range registrySellers {
+		if sub.SubscriberID == "" {
+			summary.MalformedRecords++
+			continue
+		}
+		// An unparseable validity keeps the stored bound, or is stored as NULL for a new seller,
+		// rather than dropping the seller.
+		validity, err := subscriberValidity(sub)
+		if err != nil {
+			log.Warnf(ctx, "Registry record for seller %s in domain %s is malformed: %v", sub.SubscriberID, domain, err)
+			summary.MalformedRecords++
 		}
+		stored := dbSellerMap[sub.SubscriberID]
+		validFrom, validUntil := validity.keepStored(stored.ValidFrom, stored.ValidUntil)
+		raw, _ := json.Marshal(sub)
 
-		var sellersToInsert []sellerPorts.Seller
-		var sellersToUpdate []sellerPorts.Seller
-		var removedSellerIDs []string
+		subscriberType := sub.Type
+		if subscriberType == "" {
+			subscriberType = SubscriberTypeBPP
+		}
 
-		for id, seller := range registrySellerMap {
-			if _, exists := dbSellerMap[id]; !exists {
-				sellersToInsert = append(sellersToInsert, seller)
-			} else {
-				sellersToUpdate = append(sellersToUpdate, seller)
-			}
+		seller := sellerPorts.Seller{
+			SellerID: sub.SubscriberID, Domain: sub.Domain,
+			Status: sub.Status, Type: subscriberType, SubscriberURL: sub.SubscriberURL,
+			BrID: sub.BrID, UkID: sub.UkID, SigningKey: sub.SigningKey, EncryptionKey: sub.EncryptionKey,
+			Country: sub.Country, City: sub.City, ValidFrom: validFrom, ValidUntil: validUntil,
+			Active: true, LastSeenInReg: now, RegistryRaw: string(raw),
+		}
+		if err := validateSubscriberURL(sub.SubscriberURL); err != nil {
+			message := err.Error()
+			seller.URLError = &message
 		}
+		registrySellerMap[seller.SellerID] = seller
+	}
 
-		for id := range dbSellerMap {
-			if _, exists := registrySellerMap[id]; !exists {
-				removedSellerIDs = append(removedSellerIDs, id)
-			}
+	rec := sellerPorts.DomainReconciliation{Domain: domain, SeenAt: now}
+	var changes []sellerPorts.SellerChange
+	for id, seller := range registrySellerMap {
+		existing, exists := dbSellerMap[id]
+		if !exists {
+			rec.Upserts = append(rec.Upserts, seller)
+			rec.NewSellerIDs = append(rec.NewSellerIDs, id)
+			changes = append(changes, newSellerChange(seller))
+			continue
 		}
 
-		summary.NewSellers = len(sellersToInsert)
-		summary.UpdatedSellers = len(sellersToUpdate)
-		summary.DeactivatedSellers = len(removedSellerIDs)
+		sellerChanges := diffSeller(existing, seller)
+		switch {
+		case len(sellerChanges) == 0:
+			rec.UnchangedSellerIDs = append(rec.UnchangedSellerIDs, id)
+			continue
+		case !existing.Active:
+			summary.ReactivatedSellers++
+		default:
+			summary.UpdatedSellers++
+		}
+		rec.Upserts = append(rec.Upserts, seller)
+		changes = append(changes, sellerChanges...)
+	}
 
-		if len(sellersToInsert) > 0 {
-			if err := s.repo.InsertSellers(sellersToInsert); err != nil {
-				log.Error(context.Background(), err, "Failed to insert new sellers")
+	// Absence only means a seller left the registry when the lookup covered it. A truncated
+	// lookup proves nothing, and a filtered one only speaks for its own countries and city.
+	if !complete {
+		domainErrors = append(domainErrors, fmt.Sprintf("lookup stopped after %d pages; no sellers were deactivated", s.config.RegistryMaxPages))
+	} else {
+		for id, seller := range dbSellerMap {
+			if _, exists := registrySellerMap[id]; exists || !seller.Active || !params.inScope(seller) {
+				continue
 			}
+			rec.RemovedSellerIDs = append(rec.RemovedSellerIDs, id)
+			changes = append(changes, deactivatedSellerChange(id, seller.Domain))
 		}
-		if len(sellersToUpdate) > 0 {
-			if err := s.repo.UpdateSellers(sellersToUpdate); err != nil {
-				log.Error(context.Background(), err, "Failed to update existing sellers")
-			}
+	}
+
+	for i := range changes {
+		changes[i].ChangedAt = now
+		if run != nil {
+			changes[i].RunID = &run.ID
 		}
-		for _, seller := range sellersToInsert {
-			state := &sellerPorts.SellerCatalogState{
-				SellerID: seller.SellerID, Domain: domain,
-				Status: sellerPorts.CatalogStatusNotSynced,
-			}
-			if err := s.repo.UpsertCatalogState(state); err != nil {
-				log.Error(context.Background(), err, "Failed to insert catalog state")
-			}
+	}
+	rec.Changes = changes
+
+	// The whole domain is applied in one transaction, so a failure leaves it as it was.
+	if err := s.repo.ReconcileDomainSellers(ctx, rec); err != nil {
+		log.Error(ctx, err, fmt.Sprintf("Failed to reconcile sellers for domain %s", domain))
+		domainErrors = append(domainErrors, err.Error())
+		summary.UpdatedSellers = 0
+		summary.ReactivatedSellers = 0
+		return finishDomain(sellerPorts.SyncStatusFailed)
+	}
+
+	for _, seller := range registrySellerMap {
+		if seller.URLError != nil {
+			summary.InvalidURLSellers++
 		}
-		if len(removedSellerIDs) > 0 {
-			if err := s.repo.DeactivateSellers(removedSellerIDs, domain); err != nil {
-				log.Error(context.Background(), err, "Failed to deactivate sellers")
-			}
+	}
+	if summary.InvalidURLSellers > 0 {
+		log.Warnf(ctx, "%d sellers in domain %s have an unusable subscriber_url", summary.InvalidURLSellers, domain)
+	}
+
+	summary.NewSellers = len(rec.NewSellerIDs)
+	summary.UnchangedSellers = len(rec.UnchangedSellerIDs)
+	summary.DeactivatedSellers = len(rec.RemovedSellerIDs)
+	return finishDomain("")
+}
+
+// overallSyncStatus is OK when every domain synced, FAILED when none did and PARTIAL otherwise
+func overallSyncStatus(statuses []string) string {
+	failed, degraded := 0, 0
+	for _, status := range statuses {
+		switch status {
+		case sellerPorts.SyncStatusFailed:
+			failed++
+		case sellerPorts.SyncStatusPartial:
+			degraded++
 		}
-		response.Domains = append(response.Domains, summary)
 	}
-	return response, nil
+	switch {
+	case len(statuses) > 0 && failed == len(statuses):
+		return sellerPorts.SyncStatusFailed
+	case failed > 0 || degraded > 0:
+		return sellerPorts.SyncStatusPartial
+	default:
+		return sellerPorts.SyncStatusOK
+	}
 }
 
-func (s *SellerService) FetchSellersFromRegistry(domain string) (ONDCLookupResponse, error) {
-	reqBody := ONDCLookupRequest{Country: "IND", Type: "BPP", Domain: domain}
-	authHeader, err := s.generateAuthHeader(reqBody)
+func (s *SellerService) ListRegistrySyncRuns(limit, page, offset int) (*sellerPorts.RegistrySyncRunsResponse, error) {
+	runs, err := s.repo.ListRegistrySyncRuns(limit, offset)
 	if err != nil {
 		return nil, err
 	}
-	var response ONDCLookupResponse
-	resp, err := s.client.R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("Authorization", authHeader).
-		SetBody(reqBody).
-		SetResult(&response).
-		Post(s.registryURL)
+
+	hasMore := len(runs) > limit
+	if hasMore {
+		runs = runs[:limit] // Trim the extra record fetched for hasMore check
+	}
+
+	return &sellerPorts.RegistrySyncRunsResponse{
+		Runs: runs,
+		Page: sellerPorts.PageInfo{
+			Limit:   limit,
+			Page:    page,
+			HasMore: hasMore,
+		},
+	}, nil
+}
+
+// GetSellersWithInvalidURL lists active sellers whose subscriber_url cannot be used to reach them
+func (s *SellerService) GetSellersWithInvalidURL(domain string, limit, page, offset int) (*sellerPorts.InvalidURLSellersResponse, error) {
+	sellers, err := s.repo.GetSellersWithInvalidURL(domain, limit, offset)
 	if err != nil {
 		return nil, err
 	}
-	if resp.StatusCode() != 200 {
-		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode(), resp.String())
+
+	hasMore := len(sellers) > limit
+	if hasMore {
+		sellers = sellers[:limit] // Trim the extra record fetched for hasMore check
+	}
+
+	report := make([]sellerPorts.InvalidURLSeller, 0, len(sellers))
+	for _, seller := range sellers {
+		report = append(report, sellerPorts.InvalidURLSeller{
+			SellerID:      seller.SellerID,
+			Domain:        seller.Domain,
+			SubscriberURL: seller.SubscriberURL,
+			Error:         *seller.URLError,
+		})
 	}
-	return response, nil
+
+	return &sellerPorts.InvalidURLSellersResponse{
+		Domain:  domain,
+		Sellers: report,
+		Page: sellerPorts.PageInfo{
+			Limit:   limit,
+			Page:    page,
+			HasMore: hasMore,
+		},
+	}, nil
 }
 
-func (s *SellerService) generateAuthHeader(body ONDCLookupRequest) (string, error) {
-	payload, err := json.Marshal(body)
+// GetSellerHistory returns the changes registry syncs have applied to a seller, newest first
+func (s *SellerService) GetSellerHistory(sellerID, domain string, limit, page, offset int) (*sellerPorts.SellerHistoryResponse, error) {
+	changes, err := s.repo.ListSellerChanges(sellerID, domain, limit, offset)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if s.privateKey == "" {
-		return "", fmt.Errorf("PRIVATE_KEY not configured")
+
+	hasMore := len(changes) > limit
+	if hasMore {
+		changes = changes[:limit] // Trim the extra record fetched for hasMore check
 	}
-	currentTime := int(time.Now().Unix())
-	ttl := 30
-	signature, err := s.crypto.SignRequest(s.privateKey, payload, currentTime, ttl)
+
+	return &sellerPorts.SellerHistoryResponse{
+		SellerID: sellerID,
+		Domain:   domain,
+		Changes:  changes,
+		Page: sellerPorts.PageInfo{
+			Limit:   limit,
+			Page:    page,
+			HasMore: hasMore,
+		},
+	}, nil
+}
+
+func (s *SellerService) GetRegistrySyncRun(runID uuid.UUID) (*sellerPorts.RegistrySyncRun, error) {
+	return s.repo.GetRegistrySyncRunByID(runID)
+}
+
+// GetRegistrySyncRunChanges lists the seller changes a registry sync run applied, together with
+// the trigger that started the run
+func (s *SellerService) GetRegistrySyncRunChanges(runID uuid.UUID, limit, page, offset int) (*sellerPorts.RegistrySyncRunChangesResponse, error) {
+	run, err := s.repo.GetRegistrySyncRunByID(runID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	authHeader := fmt.Sprintf(
-		`Signature keyId="%s|%s|ed25519",algorithm="ed25519",created="%d",expires="%d",headers="(created) (expires) digest",signature="%s"`,
-		s.subscriberID, s.uniqueKeyID, currentTime, currentTime+ttl, signature,
-	)
-	return authHeader, nil
+
+	changes, err := s.repo.ListRegistrySyncRunChanges(runID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(changes) > limit
+	if hasMore {
+		changes = changes[:limit] // Trim the extra record fetched for hasMore check
+	}
+
+	return &sellerPorts.RegistrySyncRunChangesResponse{
+		RunID:   runID.String(),
+		Trigger: run.Trigger,
+		Changes: changes,
+		Page: sellerPorts.PageInfo{
+			Limit:   limit,
+			Page:    page,
+			HasMore: hasMore,
+		},
+	}, nil
 }
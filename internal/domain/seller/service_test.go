@@ -0,0 +1,429 @@
+package seller_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	sellerDomain "adapter/internal/domain/seller"
+	buyerPorts "adapter/internal/ports/buyer"
+	sellerPorts "adapter/internal/ports/seller"
+	"adapter/internal/testharness"
+
+	"github.com/google/uuid"
+)
+
+func newSellerService(t *testing.T) (*sellerDomain.SellerService, *testharness.Registry, *testharness.SellerRepository, *testharness.PermissionsRepository) {
+	t.Helper()
+	registry := testharness.NewRegistry(t)
+	cfg := testharness.NewConfig(t, registry)
+	sellers := testharness.NewSellerRepository()
+	baps := testharness.NewPermissionsRepository(sellers)
+	return sellerDomain.NewSellerService(sellers, baps, cfg), registry, sellers, baps
+}
+
+func bppEntry(id, url string) testharness.RegistryEntry {
+	return testharness.RegistryEntry{
+		SubscriberID:  id,
+		SubscriberURL: url,
+		Type:          "BPP",
+		UkID:          id + "-key",
+		Domain:        testharness.TestDomain,
+		Country:       "IND",
+		City:          "std:080",
+		SigningKey:    "c2lnbmluZy1rZXk=",
+		Status:        "SUBSCRIBED",
+		ValidFrom:     "2024-01-01T00:00:00.000Z",
+		ValidUntil:    "2099-01-01T00:00:00.000Z",
+	}
+}
+
+func syncDomain(t *testing.T, service *sellerDomain.SellerService, req sellerPorts.SellerRegistrySyncRequest) *sellerPorts.SellerRegistrySyncResponse {
+	t.Helper()
+	if len(req.Domains) == 0 {
+		req.Domains = []string{testharness.TestDomain}
+	}
+	response, err := service.SyncRegistry(context.Background(), req, sellerPorts.RegistrySyncTriggerAPI)
+	if err != nil {
+		t.Fatalf("SyncRegistry: %v", err)
+	}
+	return response
+}
+
+func TestSyncRegistryReconcilesSellers(t *testing.T) {
+	service, registry, sellers, _ := newSellerService(t)
+
+	malformed := bppEntry("seller-3.example", "https://seller-3.example/ondc")
+	malformed.ValidUntil = "next tuesday"
+	registry.SetEntries(
+		bppEntry("seller-1.example", "https://seller-1.example/ondc"),
+		bppEntry("seller-2.example", "seller-2.example/ondc"),
+		malformed,
+		bppEntry("", "https://anonymous.example"),
+	)
+
+	response := syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{})
+	if response.Status != sellerPorts.SyncStatusOK {
+		t.Fatalf("status = %s, want OK: %+v", response.Status, response.Domains)
+	}
+	summary := response.Domains[0]
+	if summary.NewSellers != 3 || summary.InvalidURLSellers != 1 || summary.MalformedRecords != 2 || response.MalformedRecords != 2 {
+		t.Errorf("unexpected first sync summary: %+v", summary)
+	}
+
+	lookups := registry.Lookups()
+	if len(lookups) != 1 || lookups[0].Country != "IND" || lookups[0].Type != "BPP" || lookups[0].Domain != testharness.TestDomain {
+		t.Errorf("unexpected lookups: %+v", lookups)
+	}
+
+	stored, ok := sellers.Seller("seller-3.example", testharness.TestDomain)
+	if !ok || stored.ValidFrom == nil || stored.ValidUntil != nil {
+		t.Errorf("seller-3 should keep valid_from and store a NULL valid_until, got %+v", stored)
+	}
+	if stored, _ := sellers.Seller("seller-2.example", testharness.TestDomain); stored.URLError == nil {
+		t.Error("seller-2 should be flagged with a url_error")
+	}
+
+	// seller-1 moves, seller-2 leaves the registry and seller-3 stays as it was.
+	registry.SetEntries(bppEntry("seller-1.example", "https://seller-1.example/v2"), malformed)
+	response = syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{})
+	summary = response.Domains[0]
+	if summary.UpdatedSellers != 1 || summary.DeactivatedSellers != 1 || summary.UnchangedSellers != 1 || summary.NewSellers != 0 {
+		t.Errorf("unexpected second sync summary: %+v", summary)
+	}
+	if stored, _ := sellers.Seller("seller-2.example", testharness.TestDomain); stored.Active {
+		t.Error("seller-2 should be deactivated")
+	}
+
+	runID, err := uuid.Parse(response.RunID)
+	if err != nil {
+		t.Fatalf("run_id %q: %v", response.RunID, err)
+	}
+	runChanges, err := service.GetRegistrySyncRunChanges(runID, 10, 1, 0)
+	if err != nil {
+		t.Fatalf("GetRegistrySyncRunChanges: %v", err)
+	}
+	var changedSellers []string
+	for _, change := range runChanges.Changes {
+		if n := len(changedSellers); n == 0 || changedSellers[n-1] != change.SellerID {
+			changedSellers = append(changedSellers, change.SellerID)
+		}
+	}
+	if runChanges.Trigger != sellerPorts.RegistrySyncTriggerAPI || !reflect.DeepEqual(changedSellers, []string{"seller-1.example", "seller-2.example"}) {
+		t.Errorf("run changes by %s touched %v, want seller-1 and seller-2 by API", runChanges.Trigger, changedSellers)
+	}
+
+	history, err := service.GetSellerHistory("seller-1.example", testharness.TestDomain, 10, 1, 0)
+	if err != nil {
+		t.Fatalf("GetSellerHistory: %v", err)
+	}
+	if len(history.Changes) != 2 || history.Changes[0].Field != "subscriber_url" || *history.Changes[0].NewValue != "https://seller-1.example/v2" {
+		t.Errorf("unexpected history: %+v", history.Changes)
+	}
+
+	// A seller returning to the registry is reactivated rather than created again.
+	registry.SetEntries(bppEntry("seller-1.example", "https://seller-1.example/v2"), bppEntry("seller-2.example", "https://seller-2.example/ondc"), malformed)
+	response = syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{})
+	if summary := response.Domains[0]; summary.ReactivatedSellers != 1 || summary.NewSellers != 0 {
+		t.Errorf("unexpected third sync summary: %+v", summary)
+	}
+
+	runs, err := service.ListRegistrySyncRuns(10, 1, 0)
+	if err != nil {
+		t.Fatalf("ListRegistrySyncRuns: %v", err)
+	}
+	if len(runs.Runs) != 3 || runs.Runs[2].MalformedRecords != 2 {
+		t.Errorf("unexpected sync runs: %+v", runs.Runs)
+	}
+}
+
+func TestSyncRegistryFollowsPages(t *testing.T) {
+	registry := testharness.NewRegistry(t)
+	cfg := testharness.NewConfig(t, registry)
+	cfg.RegistryPageSize = 2
+	sellers := testharness.NewSellerRepository()
+	service := sellerDomain.NewSellerService(sellers, nil, cfg)
+
+	var entries []testharness.RegistryEntry
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		entries = append(entries, bppEntry(id+".example", "https://"+id+".example/ondc"))
+	}
+	registry.SetEntries(entries...)
+	registry.WrapResponse(true)
+
+	response := syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{})
+	if response.Domains[0].NewSellers != 5 {
+		t.Errorf("new sellers = %d, want 5", response.Domains[0].NewSellers)
+	}
+	lookups := registry.Lookups()
+	if len(lookups) != 3 {
+		t.Fatalf("lookups = %d, want 3 pages", len(lookups))
+	}
+	for i, lookup := range lookups {
+		if lookup.Page != i+1 || lookup.Limit != 2 {
+			t.Errorf("lookup %d asked for page %d limit %d", i, lookup.Page, lookup.Limit)
+		}
+	}
+}
+
+func TestSyncRegistryStopsWhenPagesRepeat(t *testing.T) {
+	registry := testharness.NewRegistry(t)
+	cfg := testharness.NewConfig(t, registry)
+	service := sellerDomain.NewSellerService(testharness.NewSellerRepository(), nil, cfg)
+	registry.SetEntries(
+		bppEntry("a.example", "https://a.example/ondc"),
+		bppEntry("b.example", "https://b.example/ondc"),
+		bppEntry("gone.example", "https://gone.example/ondc"),
+	)
+	syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{})
+
+	// A registry that ignores paging answers every page with the same full list.
+	registry.SetEntries(bppEntry("a.example", "https://a.example/ondc"), bppEntry("b.example", "https://b.example/ondc"))
+	registry.IgnorePaging(true)
+	cfg.RegistryPageSize = 2
+	lookupsBefore := len(registry.Lookups())
+
+	response := syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{})
+	if summary := response.Domains[0]; summary.Status != sellerPorts.SyncStatusOK || summary.DeactivatedSellers != 1 {
+		t.Errorf("summary = %+v, want a complete lookup that deactivates gone.example", summary)
+	}
+	if lookups := len(registry.Lookups()) - lookupsBefore; lookups != 2 {
+		t.Errorf("lookups = %d, want 2 pages", lookups)
+	}
+}
+
+func TestSyncRegistryOverridesLookup(t *testing.T) {
+	service, registry, _, _ := newSellerService(t)
+	other := bppEntry("seller-sg.example", "https://seller-sg.example/ondc")
+	other.Country = "SGP"
+	registry.SetEntries(bppEntry("seller-1.example", "https://seller-1.example/ondc"), other)
+
+	response := syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{Countries: []string{"IND", "SGP"}, City: "std:080"})
+	if response.Domains[0].NewSellers != 2 {
+		t.Errorf("new sellers = %d, want 2", response.Domains[0].NewSellers)
+	}
+	lookups := registry.Lookups()
+	if len(lookups) != 2 || lookups[1].Country != "SGP" || lookups[1].City != "std:080" {
+		t.Errorf("unexpected lookups: %+v", lookups)
+	}
+}
+
+func TestSyncRegistryFailsWhenRegistryRejectsSignature(t *testing.T) {
+	registry := testharness.NewRegistry(t)
+	cfg := testharness.NewConfig(t, nil)
+	cfg.RegistryURL = registry.URL()
+	service := sellerDomain.NewSellerService(testharness.NewSellerRepository(), nil, cfg)
+	registry.SetEntries(bppEntry("seller-1.example", "https://seller-1.example/ondc"))
+
+	response := syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{})
+	if response.Status != sellerPorts.SyncStatusFailed {
+		t.Fatalf("status = %s, want FAILED", response.Status)
+	}
+	if err := response.Domains[0].Error; err == nil || !strings.Contains(*err, "401") {
+		t.Errorf("domain error should report the 401, got %v", err)
+	}
+
+	// A v1 registry takes unsigned lookups.
+	registry.RequireSignature(false)
+	cfg.RegistryLookupVersion = "v1"
+	if response := syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{}); response.Status != sellerPorts.SyncStatusOK {
+		t.Errorf("unsigned v1 lookup status = %s, want OK", response.Status)
+	}
+}
+
+func TestSyncRegistryReportsPartialFailure(t *testing.T) {
+	service, registry, _, _ := newSellerService(t)
+	registry.FailWith(http.StatusBadGateway)
+
+	response := syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{})
+	if response.Status != sellerPorts.SyncStatusFailed || response.Domains[0].Status != sellerPorts.SyncStatusFailed {
+		t.Errorf("unexpected response for a failing registry: %+v", response)
+	}
+}
+
+func TestSyncRegistryStopsWhenCancelled(t *testing.T) {
+	service, registry, sellers, _ := newSellerService(t)
+	registry.SetEntries(bppEntry("seller-1.example", "https://seller-1.example/ondc"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := service.SyncRegistry(ctx, sellerPorts.SellerRegistrySyncRequest{Domains: []string{testharness.TestDomain}}, sellerPorts.RegistrySyncTriggerCron)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("SyncRegistry error = %v, want context.Canceled", err)
+	}
+	if len(registry.Lookups()) != 0 {
+		t.Errorf("cancelled sync looked up the registry: %+v", registry.Lookups())
+	}
+	if _, ok := sellers.Seller("seller-1.example", testharness.TestDomain); ok {
+		t.Error("cancelled sync stored a seller")
+	}
+
+	runs, err := service.ListRegistrySyncRuns(10, 1, 0)
+	if err != nil {
+		t.Fatalf("ListRegistrySyncRuns: %v", err)
+	}
+	if len(runs.Runs) != 1 || runs.Runs[0].Status != sellerPorts.SyncStatusFailed || runs.Runs[0].FinishedAt == nil {
+		t.Errorf("cancelled run was not recorded as failed: %+v", runs.Runs)
+	}
+}
+
+func TestSyncRegistrySyncsBaps(t *testing.T) {
+	service, registry, sellers, baps := newSellerService(t)
+
+	bap := bppEntry("buyer.example", "https://buyer.example/ondc")
+	bap.Type = "BAP"
+	unsubscribed := bppEntry("buyer.example", "https://buyer.example/old")
+	unsubscribed.Type = "BAP"
+	unsubscribed.UkID = "old-key"
+	unsubscribed.Status = "INITIATED"
+	registry.SetEntries(unsubscribed, bap, bppEntry("seller-1.example", "https://seller-1.example/ondc"))
+
+	response := syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{Types: []string{"BAP"}})
+	if len(response.Domains) != 0 || len(response.Baps) != 1 {
+		t.Fatalf("unexpected response: %+v", response)
+	}
+	if summary := response.Baps[0]; summary.TotalInRegistry != 2 || summary.Upserted != 1 {
+		t.Errorf("unexpected BAP summary: %+v", summary)
+	}
+	if _, ok := sellers.Seller("seller-1.example", testharness.TestDomain); ok {
+		t.Error("a BAP-only sync should not touch sellers")
+	}
+
+	stored, err := baps.FindBapByID("buyer.example")
+	if err != nil {
+		t.Fatalf("FindBapByID: %v", err)
+	}
+	if stored.Status != "SUBSCRIBED" || stored.UkID != "buyer.example-key" || stored.ValidUntil == nil {
+		t.Errorf("the SUBSCRIBED entry should win, got %+v", stored)
+	}
+	if status := stored.RegistryStatus(*stored.LastSeenInReg, time.Hour); status != "" {
+		t.Errorf("registry status = %q, want subscribed", status)
+	}
+	if status := (*buyerPorts.Bap)(nil).RegistryStatus(*stored.LastSeenInReg, time.Hour); status != buyerPorts.BapStatusUnregistered {
+		t.Errorf("unknown BAP status = %q", status)
+	}
+	if status := stored.RegistryStatus(stored.LastSeenInReg.Add(2*time.Hour), time.Hour); status != buyerPorts.BapStatusUnregistered {
+		t.Errorf("a BAP no sync has seen within the max age should be unregistered, got %q", status)
+	}
+
+	// The BAP is only pending in a second domain; the result must not depend on domain order.
+	other := unsubscribed
+	other.Domain = "ONDC:RET11"
+	registry.SetEntries(bap, other)
+	for _, domains := range [][]string{{testharness.TestDomain, "ONDC:RET11"}, {"ONDC:RET11", testharness.TestDomain}} {
+		syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{Domains: domains, Types: []string{"BAP"}})
+		if stored, _ := baps.FindBapByID("buyer.example"); stored.Status != "SUBSCRIBED" {
+			t.Errorf("syncing %v left the BAP %s", domains, stored.Status)
+		}
+	}
+}
+
+func TestSyncRegistryRejectsUnsupportedType(t *testing.T) {
+	service, _, _, _ := newSellerService(t)
+	_, err := service.SyncRegistry(context.Background(), sellerPorts.SellerRegistrySyncRequest{Domains: []string{testharness.TestDomain}, Types: []string{"BG"}}, sellerPorts.RegistrySyncTriggerAPI)
+	if !errors.Is(err, sellerDomain.ErrUnsupportedSubscriberType) {
+		t.Errorf("err = %v, want ErrUnsupportedSubscriberType", err)
+	}
+}
+
+func TestSyncRegistryOnlyDeactivatesWithinLookupScope(t *testing.T) {
+	registry := testharness.NewRegistry(t)
+	cfg := testharness.NewConfig(t, registry)
+	cfg.RegistryMaxPages = 1
+	sellers := testharness.NewSellerRepository()
+	service := sellerDomain.NewSellerService(sellers, nil, cfg)
+
+	delhi := bppEntry("seller-del.example", "https://seller-del.example/ondc")
+	delhi.City = "std:011"
+	registry.SetEntries(bppEntry("seller-blr.example", "https://seller-blr.example/ondc"), delhi)
+	if response := syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{}); response.Domains[0].NewSellers != 2 {
+		t.Fatalf("unexpected initial sync: %+v", response.Domains[0])
+	}
+
+	// A city-scoped sync must leave sellers of other cities alone.
+	response := syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{City: "std:080"})
+	if summary := response.Domains[0]; summary.DeactivatedSellers != 0 || summary.UnchangedSellers != 1 {
+		t.Errorf("unexpected city-scoped summary: %+v", summary)
+	}
+
+	// A lookup cut off at REGISTRY_MAX_PAGES is partial and deactivates nothing.
+	cfg.RegistryPageSize = 1
+	response = syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{})
+	if summary := response.Domains[0]; summary.Status != sellerPorts.SyncStatusPartial || summary.DeactivatedSellers != 0 || summary.Error == nil {
+		t.Errorf("unexpected truncated summary: %+v", summary)
+	}
+	for _, id := range []string{"seller-blr.example", "seller-del.example"} {
+		if stored, _ := sellers.Seller(id, testharness.TestDomain); !stored.Active {
+			t.Errorf("%s should still be active", id)
+		}
+	}
+
+	// A complete lookup of the city still removes sellers of that city that left the registry.
+	cfg.RegistryPageSize = 0
+	registry.SetEntries(delhi)
+	response = syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{City: "std:080"})
+	if summary := response.Domains[0]; summary.DeactivatedSellers != 1 {
+		t.Errorf("unexpected summary after seller-blr left: %+v", summary)
+	}
+}
+
+func TestSyncRegistryKeepsStoredValidityWhenMalformed(t *testing.T) {
+	service, registry, sellers, baps := newSellerService(t)
+
+	seller := bppEntry("seller-1.example", "https://seller-1.example/ondc")
+	bap := bppEntry("buyer.example", "https://buyer.example/ondc")
+	bap.Type = "BAP"
+	registry.SetEntries(seller, bap)
+	syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{Types: []string{"BPP", "BAP"}})
+
+	want := time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+	seller.ValidUntil = "sometime next year"
+	bap.ValidUntil = "sometime next year"
+	registry.SetEntries(seller, bap)
+	response := syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{Types: []string{"BPP", "BAP"}})
+	if response.MalformedRecords != 2 {
+		t.Errorf("malformed records = %d, want 2", response.MalformedRecords)
+	}
+	if stored, _ := sellers.Seller("seller-1.example", testharness.TestDomain); stored.ValidUntil == nil || !stored.ValidUntil.Equal(want) {
+		t.Errorf("seller valid_until = %v, want the stored %v", stored.ValidUntil, want)
+	}
+	if stored, _ := baps.FindBapByID("buyer.example"); stored.ValidUntil == nil || !stored.ValidUntil.Equal(want) {
+		t.Errorf("BAP valid_until = %v, want the stored %v", stored.ValidUntil, want)
+	}
+
+	// A bound the registry no longer sends is cleared.
+	seller.ValidUntil = ""
+	bap.ValidUntil = ""
+	registry.SetEntries(seller, bap)
+	syncDomain(t, service, sellerPorts.SellerRegistrySyncRequest{Types: []string{"BPP", "BAP"}})
+	if stored, _ := sellers.Seller("seller-1.example", testharness.TestDomain); stored.ValidUntil != nil {
+		t.Errorf("seller valid_until = %v, want NULL", stored.ValidUntil)
+	}
+	if stored, _ := baps.FindBapByID("buyer.example"); stored.ValidUntil != nil {
+		t.Errorf("BAP valid_until = %v, want NULL", stored.ValidUntil)
+	}
+}
+
+func TestResolveSigningKeyCachesMisses(t *testing.T) {
+	service, registry, _, _ := newSellerService(t)
+	registry.SetEntries(bppEntry("seller-1.example", "https://seller-1.example/ondc"))
+	resolver := sellerDomain.NewSigningKeyResolver(service, testharness.NewCache(), time.Hour, time.Minute)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := resolver.ResolveSigningKey(ctx, "seller-1.example", "made-up-key"); !errors.Is(err, sellerDomain.ErrSigningKeyNotFound) {
+			t.Fatalf("ResolveSigningKey: err = %v, want ErrSigningKeyNotFound", err)
+		}
+	}
+	if lookups := registry.Lookups(); len(lookups) != 1 {
+		t.Errorf("made-up keyId caused %d registry lookups, want 1", len(lookups))
+	}
+
+	if key, err := resolver.ResolveSigningKey(ctx, "seller-1.example", "seller-1.example-key"); err != nil || key == "" {
+		t.Errorf("ResolveSigningKey for a real key = %q, %v", key, err)
+	}
+}
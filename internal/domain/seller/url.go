@@ -0,0 +1,25 @@
+package seller
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// validateSubscriberURL checks that a registry subscriber_url can be used as-is to reach the
+// subscriber, i.e. it is an absolute http(s) URL with a host.
+func validateSubscriberURL(raw string) error {
+	if raw == "" {
+		return fmt.Errorf("subscriber_url is empty")
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("subscriber_url is not a valid URL: %v", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("subscriber_url must use http or https, got %q", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("subscriber_url has no host")
+	}
+	return nil
+}
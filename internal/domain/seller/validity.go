@@ -0,0 +1,51 @@
+package seller
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"adapter/internal/shared/utils"
+)
+
+// registryValidity is the validity window of a registry record. Empty bounds are nil; a bound in
+// an unrecognised format is nil as well and flagged as malformed.
+type registryValidity struct {
+	From, Until                   *time.Time
+	FromMalformed, UntilMalformed bool
+}
+
+// keepStored replaces malformed bounds with the stored ones, so that one bad sync does not erase
+// a window that was read correctly before. Zero stored times predate nullable validity and count
+// as unknown.
+func (v registryValidity) keepStored(storedFrom, storedUntil *time.Time) (validFrom, validUntil *time.Time) {
+	validFrom, validUntil = v.From, v.Until
+	if v.FromMalformed && storedFrom != nil && !storedFrom.IsZero() {
+		validFrom = storedFrom
+	}
+	if v.UntilMalformed && storedUntil != nil && !storedUntil.IsZero() {
+		validUntil = storedUntil
+	}
+	return validFrom, validUntil
+}
+
+// subscriberValidity parses the validity window of a registry record. Malformed bounds are
+// reported in the returned error.
+func subscriberValidity(sub Subscriber) (registryValidity, error) {
+	var errs []error
+	parse := func(field, value string) (*time.Time, bool) {
+		if value == "" {
+			return nil, false
+		}
+		t, err := utils.ParseRegistryTime(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", field, err))
+			return nil, true
+		}
+		return &t, false
+	}
+	var v registryValidity
+	v.From, v.FromMalformed = parse("valid_from", sub.ValidFrom)
+	v.Until, v.UntilMalformed = parse("valid_until", sub.ValidUntil)
+	return v, errors.Join(errs...)
+}
@@ -1,8 +1,16 @@
 package broadcast
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
 	broadcastDomain "adapter/internal/domain/broadcast"
 	broadcastPorts "adapter/internal/ports/broadcast"
+	buyerPorts "adapter/internal/ports/buyer"
 	"adapter/internal/shared/constants"
 	"adapter/internal/shared/utils"
 
@@ -28,7 +36,20 @@ func (h *BroadcastHandler) BroadcastPermissions(c *fiber.Ctx) error {
 		})
 	}
 
-	job, err := h.service.BroadcastPermissions(req)
+	signerID, _ := c.Locals("subscriber_id").(string)
+	job, err := h.service.BroadcastPermissions(req, signerID)
+	if errors.Is(err, broadcastDomain.ErrInvalidCallbackURL) {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+	if errors.Is(err, buyerPorts.ErrBapNotRegistered) || errors.Is(err, buyerPorts.ErrBapIDMismatch) {
+		return c.Status(fiber.StatusForbidden).JSON(utils.ApiResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
 			Success: false,
@@ -42,6 +63,16 @@ func (h *BroadcastHandler) BroadcastPermissions(c *fiber.Ctx) error {
 	})
 }
 
+// callerBapID identifies the BAP a job lookup is made for. A signature-verified caller is the
+// signing subscriber; otherwise the bap_id query parameter names it.
+func callerBapID(c *fiber.Ctx) (string, bool) {
+	if subscriberID, ok := c.Locals("subscriber_id").(string); ok {
+		return subscriberID, true
+	}
+	bapID := c.Query("bap_id")
+	return bapID, bapID != ""
+}
+
 func (h *BroadcastHandler) GetBroadcastStatus(c *fiber.Ctx) error {
 	jobIDStr := c.Params("job_id")
 	jobID, err := uuid.Parse(jobIDStr)
@@ -52,7 +83,22 @@ func (h *BroadcastHandler) GetBroadcastStatus(c *fiber.Ctx) error {
 		})
 	}
 
-	job, err := h.service.GetBroadcastStatus(jobID)
+	bapID, ok := callerBapID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrBapIDRequired,
+		})
+	}
+
+	limit := utils.ClampLimit(c.QueryInt("limit", utils.DefaultPageLimit))
+	page := c.QueryInt("page", 1)
+	offset := (page - 1) * limit
+	if offset < 0 {
+		offset = 0
+	}
+
+	response, err := h.service.GetBroadcastStatus(jobID, bapID, c.Query("outcome"), limit, page, offset)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(utils.ApiResponse{
@@ -69,6 +115,246 @@ func (h *BroadcastHandler) GetBroadcastStatus(c *fiber.Ctx) error {
 	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
 		Success: true,
 		Message: "Broadcast job status fetched successfully",
+		Data:    response,
+	})
+}
+
+func (h *BroadcastHandler) CancelBroadcast(c *fiber.Ctx) error {
+	jobID, err := uuid.Parse(c.Params("job_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+			Success: false,
+			Message: "Invalid job_id format",
+		})
+	}
+
+	bapID, ok := callerBapID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrBapIDRequired,
+		})
+	}
+
+	job, err := h.service.CancelBroadcast(jobID, bapID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ApiResponse{
+				Success: false,
+				Message: "Broadcast job not found",
+			})
+		}
+		if errors.Is(err, broadcastDomain.ErrJobFinished) {
+			return c.Status(fiber.StatusConflict).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrBroadcastJobFinished,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Broadcast job cancelled successfully",
 		Data:    job,
 	})
 }
+
+// IssueEventStreamToken hands out a short-lived token for the job's event stream, for clients
+// such as a browser's EventSource that cannot sign the stream request.
+func (h *BroadcastHandler) IssueEventStreamToken(c *fiber.Ctx) error {
+	jobID, err := uuid.Parse(c.Params("job_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+			Success: false,
+			Message: "Invalid job_id format",
+		})
+	}
+
+	bapID, ok := callerBapID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrBapIDRequired,
+		})
+	}
+
+	token, err := h.service.IssueEventStreamToken(c.UserContext(), jobID, bapID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ApiResponse{
+				Success: false,
+				Message: "Broadcast job not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Event stream token issued successfully",
+		Data:    token,
+	})
+}
+
+// eventStreamAuth lets a token query parameter from IssueEventStreamToken stand in for the
+// route's usual auth, which a browser's EventSource cannot satisfy.
+func (h *BroadcastHandler) eventStreamAuth(next fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token := c.Query("token")
+		if token == "" {
+			return next(c)
+		}
+
+		jobID, err := uuid.Parse(c.Params("job_id"))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+				Success: false,
+				Message: "Invalid job_id format",
+			})
+		}
+		bapID, err := h.service.RedeemEventStreamToken(c.UserContext(), jobID, token)
+		if err != nil {
+			if errors.Is(err, broadcastDomain.ErrInvalidEventStreamToken) {
+				return c.Status(fiber.StatusUnauthorized).JSON(utils.ApiResponse{
+					Success: false,
+					Message: constants.ErrInvalidStreamToken,
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+				Success: false,
+				Message: err.Error(),
+			})
+		}
+		c.Locals("subscriber_id", bapID)
+		return c.Next()
+	}
+}
+
+// sseKeepAliveInterval keeps idle event streams open through proxies.
+const sseKeepAliveInterval = 15 * time.Second
+
+// StreamBroadcastEvents streams the job's per-seller outcomes as Server-Sent Events, ending
+// with a job_finished event.
+func (h *BroadcastHandler) StreamBroadcastEvents(c *fiber.Ctx) error {
+	jobID, err := uuid.Parse(c.Params("job_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+			Success: false,
+			Message: "Invalid job_id format",
+		})
+	}
+
+	bapID, ok := callerBapID(c)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrBapIDRequired,
+		})
+	}
+
+	// The stream outlives the handler, so it cannot use the request context; it ends when the
+	// client goes away, the job finishes or the server shuts down.
+	ctx, cancel := context.WithCancel(context.Background())
+	go func(shutdown <-chan struct{}) {
+		select {
+		case <-shutdown:
+			cancel()
+		case <-ctx.Done():
+		}
+	}(c.Context().Done())
+	events, err := h.service.SubscribeBroadcastEvents(ctx, jobID, bapID)
+	if err != nil {
+		cancel()
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ApiResponse{
+				Success: false,
+				Message: "Broadcast job not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer cancel()
+
+		keepAlive := time.NewTicker(sseKeepAliveInterval)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			}
+			// A failed flush means the client went away.
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
+
+func (h *BroadcastHandler) OnSearch(c *fiber.Ctx) error {
+	var req broadcastPorts.OnSearchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return onSearchNack(c, fiber.StatusBadRequest, constants.ErrInvalidRequestBody)
+	}
+
+	if req.Context == nil || req.Context.TransactionID == "" || req.Context.BppID == "" {
+		return onSearchNack(c, fiber.StatusBadRequest, constants.ErrOnSearchContextRequired)
+	}
+
+	// When the request was signature-verified, the signer must be the seller it claims to be.
+	signerID, _ := c.Locals("subscriber_id").(string)
+	if _, err := h.service.HandleOnSearch(req, signerID); err != nil {
+		if errors.Is(err, broadcastDomain.ErrOnSearchSignerMismatch) {
+			return onSearchNack(c, fiber.StatusUnauthorized, constants.ErrOnSearchSignerMismatch)
+		}
+		if errors.Is(err, broadcastDomain.ErrUnknownTransaction) {
+			return onSearchNack(c, fiber.StatusNotFound, err.Error())
+		}
+		if errors.Is(err, broadcastDomain.ErrNotJobTarget) {
+			return onSearchNack(c, fiber.StatusForbidden, err.Error())
+		}
+		return onSearchNack(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return c.Status(fiber.StatusOK).JSON(broadcastPorts.AckResponse{
+		Context: req.Context,
+		Message: &broadcastPorts.AckMessage{Ack: &broadcastPorts.Ack{Status: "ACK"}},
+	})
+}
+
+func onSearchNack(c *fiber.Ctx, status int, message string) error {
+	return c.Status(status).JSON(broadcastPorts.NackResponse{
+		Message: &broadcastPorts.AckMessage{Ack: &broadcastPorts.Ack{Status: "NACK"}},
+		Error: &broadcastPorts.Error{
+			Type:    "CONTEXT-ERROR",
+			Message: message,
+		},
+	})
+}
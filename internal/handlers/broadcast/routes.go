@@ -1,9 +1,19 @@
 package broadcast
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"adapter/internal/shared/middleware"
 
-func (h *BroadcastHandler) RegisterRoutes(app *fiber.App) {
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *BroadcastHandler) RegisterRoutes(app *fiber.App, auth middleware.RouteAuth) {
 	routes := app.Group("/v1")
-	routes.Post("/permissions/broadcast", h.BroadcastPermissions)
-	routes.Get("/permissions/broadcast/status/:job_id", h.GetBroadcastStatus)
+	routes.Post("/permissions/broadcast", auth.Public, h.BroadcastPermissions)
+	routes.Get("/permissions/broadcast/status/:job_id", auth.Public, h.GetBroadcastStatus)
+	routes.Post("/permissions/broadcast/:job_id/cancel", auth.Public, h.CancelBroadcast)
+	routes.Post("/permissions/broadcast/:job_id/events/token", auth.Public, h.IssueEventStreamToken)
+	routes.Get("/permissions/broadcast/:job_id/events", h.eventStreamAuth(auth.Public), h.StreamBroadcastEvents)
+
+	// ONDC callbacks are received on the subscriber URL itself, outside the versioned API.
+	app.Post("/on_search", auth.Public, h.OnSearch)
 }
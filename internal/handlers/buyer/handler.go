@@ -1,6 +1,8 @@
 package buyer
 
 import (
+	"errors"
+
 	buyerDomain "adapter/internal/domain/buyer"
 	buyerPorts "adapter/internal/ports/buyer"
 	sellerPorts "adapter/internal/ports/seller"
@@ -36,7 +38,14 @@ func (h *BuyerHandler) UpdateBapAccessPermissions(c *fiber.Ctx) error {
 		})
 	}
 
-	results, err := h.permissionsService.UpdateBapAccessPermissions(req.Updates)
+	signerID, _ := c.Locals("subscriber_id").(string)
+	results, err := h.permissionsService.UpdateBapAccessPermissions(req.Updates, signerID)
+	if errors.Is(err, buyerPorts.ErrBapIDMismatch) {
+		return c.Status(fiber.StatusForbidden).JSON(utils.ApiResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
 			Success: false,
@@ -67,7 +76,14 @@ func (h *BuyerHandler) QueryBapAccessPermissions(c *fiber.Ctx) error {
 		})
 	}
 
-	response, err := h.permissionsService.QueryBapAccessPermissions(req)
+	signerID, _ := c.Locals("subscriber_id").(string)
+	response, err := h.permissionsService.QueryBapAccessPermissions(req, signerID)
+	if errors.Is(err, buyerPorts.ErrBapNotRegistered) || errors.Is(err, buyerPorts.ErrBapIDMismatch) {
+		return c.Status(fiber.StatusForbidden).JSON(utils.ApiResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
 			Success: false,
@@ -1,9 +1,13 @@
 package buyer
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"adapter/internal/shared/middleware"
 
-func (h *BuyerHandler) RegisterRoutes(app *fiber.App) {
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *BuyerHandler) RegisterRoutes(app *fiber.App, auth middleware.RouteAuth) {
 	routes := app.Group("/v1")
-	routes.Post("/permissions", h.UpdateBapAccessPermissions)
-	routes.Post("/permissions/query", h.QueryBapAccessPermissions)
+	routes.Post("/permissions", auth.Public, h.UpdateBapAccessPermissions)
+	routes.Post("/permissions/query", auth.Public, h.QueryBapAccessPermissions)
 }
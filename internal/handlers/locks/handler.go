@@ -0,0 +1,35 @@
+package locks
+
+import (
+	"adapter/internal/shared/constants"
+	"adapter/internal/shared/lock"
+	"adapter/internal/shared/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type LockHandler struct {
+	locker *lock.Locker
+}
+
+func NewLockHandler(locker *lock.Locker) *LockHandler {
+	return &LockHandler{locker: locker}
+}
+
+// ListLocks shows which replica holds each distributed lock, e.g. which cron instance is
+// running a scheduled job.
+func (h *LockHandler) ListLocks(c *fiber.Ctx) error {
+	holders, err := h.locker.Holders(c.UserContext())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrFailedToListLocks,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Locks fetched successfully",
+		Data:    holders,
+	})
+}
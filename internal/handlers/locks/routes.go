@@ -0,0 +1,12 @@
+package locks
+
+import (
+	"adapter/internal/shared/middleware"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *LockHandler) RegisterRoutes(app *fiber.App, auth middleware.RouteAuth) {
+	routes := app.Group("/v1")
+	routes.Get("/internal/locks", auth.Internal, h.ListLocks)
+}
@@ -1,6 +1,8 @@
 package seller
 
 import (
+	"errors"
+
 	"adapter/internal/domain/seller"
 	sellerPorts "adapter/internal/ports/seller"
 	"adapter/internal/shared/constants"
@@ -8,6 +10,7 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 )
 
 type SellerHandler struct {
@@ -34,7 +37,13 @@ func (h *SellerHandler) SyncRegistry(c *fiber.Ctx) error {
 		})
 	}
 
-	response, err := h.sellerService.SyncRegistry(req)
+	response, err := h.sellerService.SyncRegistry(c.UserContext(), req, sellerPorts.RegistrySyncTriggerAPI)
+	if errors.Is(err, seller.ErrUnsupportedSubscriberType) {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
 			Success: false,
@@ -42,6 +51,15 @@ func (h *SellerHandler) SyncRegistry(c *fiber.Ctx) error {
 		})
 	}
 
+	// Domains succeed or fail independently; callers check each domain's status on a 207.
+	if response.Status != sellerPorts.SyncStatusOK {
+		return c.Status(fiber.StatusMultiStatus).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrRegistrySyncIncomplete,
+			Data:    response,
+		})
+	}
+
 	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
 		Success: true,
 		Message: "Registry sync completed successfully",
@@ -58,7 +76,7 @@ func (h *SellerHandler) GetPendingCatalogSyncSellers(c *fiber.Ctx) error {
 		})
 	}
 	status := c.Query("status")
-	limit := c.QueryInt("limit", 100)
+	limit := utils.ClampLimit(c.QueryInt("limit", utils.DefaultPageLimit))
 	page := c.QueryInt("page", 1)
 	offset := (page - 1) * limit
 	if offset < 0 {
@@ -110,3 +128,140 @@ func (h *SellerHandler) GetSyncStatus(c *fiber.Ctx) error {
 		Data:    response,
 	})
 }
+
+func (h *SellerHandler) ListRegistrySyncRuns(c *fiber.Ctx) error {
+	limit := utils.ClampLimit(c.QueryInt("limit", utils.DefaultPageLimit))
+	page := c.QueryInt("page", 1)
+	offset := (page - 1) * limit
+	if offset < 0 {
+		offset = 0
+	}
+
+	response, err := h.sellerService.ListRegistrySyncRuns(limit, page, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrGetRegistrySyncRuns,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Registry sync runs retrieved successfully",
+		Data:    response,
+	})
+}
+
+func (h *SellerHandler) GetRegistrySyncRun(c *fiber.Ctx) error {
+	runID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+			Success: false,
+			Message: "Invalid run id format",
+		})
+	}
+
+	run, err := h.sellerService.GetRegistrySyncRun(runID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ApiResponse{
+				Success: false,
+				Message: "Registry sync run not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrGetRegistrySyncRuns,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Registry sync run retrieved successfully",
+		Data:    run,
+	})
+}
+
+func (h *SellerHandler) GetRegistrySyncRunChanges(c *fiber.Ctx) error {
+	runID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.ApiResponse{
+			Success: false,
+			Message: "Invalid run id format",
+		})
+	}
+
+	limit := utils.ClampLimit(c.QueryInt("limit", utils.DefaultPageLimit))
+	page := c.QueryInt("page", 1)
+	offset := (page - 1) * limit
+	if offset < 0 {
+		offset = 0
+	}
+
+	response, err := h.sellerService.GetRegistrySyncRunChanges(runID, limit, page, offset)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(utils.ApiResponse{
+				Success: false,
+				Message: "Registry sync run not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrGetRegistrySyncRuns,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Registry sync run changes retrieved successfully",
+		Data:    response,
+	})
+}
+
+func (h *SellerHandler) GetSellerHistory(c *fiber.Ctx) error {
+	sellerID := c.Params("seller_id")
+	limit := utils.ClampLimit(c.QueryInt("limit", utils.DefaultPageLimit))
+	page := c.QueryInt("page", 1)
+	offset := (page - 1) * limit
+	if offset < 0 {
+		offset = 0
+	}
+
+	response, err := h.sellerService.GetSellerHistory(sellerID, c.Query("domain"), limit, page, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrGetSellerHistory,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Seller history retrieved successfully",
+		Data:    response,
+	})
+}
+
+func (h *SellerHandler) GetSellersWithInvalidURL(c *fiber.Ctx) error {
+	limit := utils.ClampLimit(c.QueryInt("limit", utils.DefaultPageLimit))
+	page := c.QueryInt("page", 1)
+	offset := (page - 1) * limit
+	if offset < 0 {
+		offset = 0
+	}
+
+	response, err := h.sellerService.GetSellersWithInvalidURL(c.Query("domain"), limit, page, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.ApiResponse{
+			Success: false,
+			Message: constants.ErrGetInvalidURLSellers,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(utils.ApiResponse{
+		Success: true,
+		Message: "Sellers with invalid subscriber_url retrieved successfully",
+		Data:    response,
+	})
+}
@@ -1,11 +1,20 @@
 package seller
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"adapter/internal/shared/middleware"
 
-func (h *SellerHandler) RegisterRoutes(app *fiber.App) {
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *SellerHandler) RegisterRoutes(app *fiber.App, auth middleware.RouteAuth) {
 	routes := app.Group("/v1")
-	routes.Get("/catalog-sync/pending", h.GetPendingCatalogSyncSellers)
-	routes.Get("/catalog-sync/sellers/:seller_id", h.GetSyncStatus)
-	internal := routes.Group("/internal")
+	routes.Get("/catalog-sync/pending", auth.Internal, h.GetPendingCatalogSyncSellers)
+	routes.Get("/catalog-sync/sellers/:seller_id", auth.Internal, h.GetSyncStatus)
+	routes.Get("/sellers/:seller_id/history", auth.Internal, h.GetSellerHistory)
+	internal := routes.Group("/internal", auth.Internal)
 	internal.Post("/registry-sync", h.SyncRegistry)
+	internal.Get("/registry-sync/runs", h.ListRegistrySyncRuns)
+	internal.Get("/registry-sync/runs/:id", h.GetRegistrySyncRun)
+	internal.Get("/registry-sync/runs/:id/sellers", h.GetRegistrySyncRunChanges)
+	internal.Get("/sellers/invalid-urls", h.GetSellersWithInvalidURL)
 }
@@ -1,5 +1,12 @@
 package broadcast
 
+import (
+	"time"
+
+	"adapter/internal/ports/buyer"
+	"adapter/internal/ports/seller"
+)
+
 // SearchPayload defines the structure for the ONDC /search request
 type SearchPayload struct {
 	Context *Context `json:"context"`
@@ -11,6 +18,7 @@ type BroadcastRequest struct {
 	SearchPayload   *SearchPayload `json:"search_payload"`
 	SellerIDs       []string       `json:"seller_ids,omitempty"`
 	IncludeNoPolicy bool           `json:"include_no_policy,omitempty"`
+	CallbackURL     string         `json:"callback_url,omitempty"`
 }
 
 // Context defines the context for the /search API
@@ -22,6 +30,8 @@ type Context struct {
 	CoreVersion   string `json:"core_version"`
 	BapID         string `json:"bap_id"`
 	BapURI        string `json:"bap_uri"`
+	BppID         string `json:"bpp_id,omitempty"`
+	BppURI        string `json:"bpp_uri,omitempty"`
 	TransactionID string `json:"transaction_id"`
 	MessageID     string `json:"message_id"`
 	Timestamp     string `json:"timestamp"`
@@ -83,6 +93,7 @@ type AckMessage struct {
 // Ack defines the ack for the /search API
 type Ack struct {
 	Status string `json:"status"`
+	Tags   []*Tag `json:"tags,omitempty"`
 }
 
 // NackResponse defines the response body for the /search API
@@ -92,9 +103,81 @@ type NackResponse struct {
 	Error   *Error      `json:"error"`
 }
 
+// OnSearchRequest defines the callback body sellers send to /on_search
+type OnSearchRequest struct {
+	Context *Context         `json:"context"`
+	Message *OnSearchMessage `json:"message"`
+	Error   *Error           `json:"error"`
+}
+
+// OnSearchMessage defines the message for the /on_search callback
+type OnSearchMessage struct {
+	Catalog *Catalog `json:"catalog"`
+}
+
+// Catalog defines the parts of a seller catalog used for access decisions
+type Catalog struct {
+	Tags []*Tag `json:"tags,omitempty"`
+}
+
 // Error defines the error for the /search API
 type Error struct {
 	Type    string `json:"type"`
 	Code    string `json:"code"`
 	Message string `json:"message"`
 }
+
+// BroadcastTargetCounts summarises the per-seller outcomes of a broadcast job
+type BroadcastTargetCounts struct {
+	Total     int `json:"total"`
+	Pending   int `json:"pending"`
+	Ack       int `json:"ack"`
+	Nack      int `json:"nack"`
+	Timeout   int `json:"timeout"`
+	Error     int `json:"error"`
+	Cancelled int `json:"cancelled"`
+	Skipped   int `json:"skipped"`
+
+	// Attempts is the total number of /search requests sent, Retried the number of sellers needing more than one.
+	Attempts int `json:"attempts"`
+	Retried  int `json:"retried"`
+}
+
+// BroadcastStatusResponse defines the response body for the /v1/permissions/broadcast/status/:job_id API
+type BroadcastStatusResponse struct {
+	*buyer.PermissionsJob
+	Counts  BroadcastTargetCounts        `json:"counts"`
+	Targets []buyer.PermissionsJobTarget `json:"targets"`
+	Page    seller.PageInfo              `json:"page"`
+}
+
+// BroadcastWebhookPayload defines the summary POSTed to a job's callback_url once it finishes.
+// Outcomes counts how sellers answered /search (ACK, NACK, ...); their access decisions arrive
+// later through on_search and are not part of the summary.
+type BroadcastWebhookPayload struct {
+	JobID      string                `json:"job_id"`
+	BapID      string                `json:"bap_id"`
+	Status     string                `json:"status"`
+	Outcomes   BroadcastTargetCounts `json:"outcomes"`
+	FinishedAt *time.Time            `json:"finished_at"`
+}
+
+const (
+	EventTypeTarget      = "target"
+	EventTypeJobFinished = "job_finished"
+)
+
+// EventStreamTokenResponse defines the response body for the
+// /v1/permissions/broadcast/:job_id/events/token API
+type EventStreamTokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// BroadcastEvent is a progress update streamed from /v1/permissions/broadcast/:job_id/events.
+// Target events carry a seller's outcome, the final job_finished event carries the job.
+type BroadcastEvent struct {
+	Type   string                      `json:"type"`
+	Target *buyer.PermissionsJobTarget `json:"target,omitempty"`
+	Job    *buyer.PermissionsJob       `json:"job,omitempty"`
+}
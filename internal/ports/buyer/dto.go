@@ -10,6 +10,10 @@ type BapPermissionsQueryRequest struct {
 	Domain          string   `json:"domain"`
 	SellerIDs       []string `json:"seller_ids"`
 	IncludeNoPolicy bool     `json:"include_no_policy"`
+	// OmitExpired leaves expired policies out of permissions instead of reporting them as EXPIRED.
+	OmitExpired bool `json:"omit_expired"`
+	// AutoRefresh queues a broadcast to the sellers listed in needs_refresh.
+	AutoRefresh bool `json:"auto_refresh"`
 }
 
 // BapPermissionsQueryResponse defines the response body for the /v1/permissions/query API
@@ -17,4 +21,17 @@ type BapPermissionsQueryResponse struct {
 	BapStatus   string                          `json:"bap_status"`
 	Domain      string                          `json:"domain"`
 	Permissions []seller.SellerPermissionDetail `json:"permissions"`
+	// NeedsRefresh lists the sellers whose policy has expired.
+	NeedsRefresh []string `json:"needs_refresh"`
+	RefreshJobID *string  `json:"refresh_job_id,omitempty"`
+}
+
+// PolicyRefreshSummary reports what a policy refresh run probed
+type PolicyRefreshSummary struct {
+	Candidates         int      `json:"candidates"`
+	Probes             int      `json:"probes"`
+	SkippedSellerLimit int      `json:"skipped_seller_limit"`
+	SkippedRunLimit    int      `json:"skipped_run_limit"`
+	FailedProbes       int      `json:"failed_probes"`
+	JobIDs             []string `json:"job_ids"`
 }
@@ -1,6 +1,7 @@
 package buyer
 
 import (
+	"errors"
 	"time"
 
 	"adapter/internal/ports/seller"
@@ -8,16 +9,70 @@ import (
 )
 
 // Bap defines the structure for a BAP (Buyer App)
+// The registry fields are only set for BAPs synced from the registry. FirstSeenAt and LastSeenAt
+// track calls to the adapter, so they stay nil for a BAP only known from the registry.
 type Bap struct {
-	BapID       string    `json:"bap_id" gorm:"primary_key"`
-	FirstSeenAt time.Time `json:"first_seen_at" gorm:"autoCreateTime"`
-	LastSeenAt  time.Time `json:"last_seen_at" gorm:"autoUpdateTime"`
+	BapID         string     `json:"bap_id" gorm:"primary_key"`
+	FirstSeenAt   *time.Time `json:"first_seen_at" gorm:"column:first_seen_at;type:timestamptz"`
+	LastSeenAt    *time.Time `json:"last_seen_at" gorm:"column:last_seen_at;type:timestamptz"`
+	LastSeenInReg *time.Time `json:"last_seen_in_reg,omitempty" gorm:"column:last_seen_in_reg;type:timestamptz"`
+	Status        string     `json:"status,omitempty" gorm:"column:status;type:text"`
+	SubscriberURL string     `json:"subscriber_url,omitempty" gorm:"column:subscriber_url;type:text"`
+	ValidFrom     *time.Time `json:"valid_from,omitempty" gorm:"column:valid_from;type:timestamptz"`
+	ValidUntil    *time.Time `json:"valid_until,omitempty" gorm:"column:valid_until;type:timestamptz"`
+	UkID          string     `json:"ukId,omitempty" gorm:"column:uk_id;type:text"`
+	SigningKey    string     `json:"signing_public_key,omitempty" gorm:"column:signing_public_key;type:text"`
+	EncryptionKey string     `json:"encr_public_key,omitempty" gorm:"column:encr_public_key;type:text"`
+
+	// KeepValidFrom and KeepValidUntil make UpsertRegistryBaps leave the stored bound alone, for
+	// registry records whose bound could not be parsed.
+	KeepValidFrom  bool `json:"-" gorm:"-"`
+	KeepValidUntil bool `json:"-" gorm:"-"`
 }
 
 func (Bap) TableName() string {
 	return "baps"
 }
 
+const (
+	BapStatusNew          = "NEW_BAP"
+	BapStatusExisting     = "EXISTING_BAP"
+	BapStatusUnregistered = "UNREGISTERED"
+	BapStatusExpired      = "EXPIRED"
+)
+
+// BAP_REGISTRY_MODE decides how bap_ids missing from the registry are treated: off ignores the
+// registry, report flags them in bap_status and strict rejects them.
+const (
+	BapRegistryModeOff    = "off"
+	BapRegistryModeReport = "report"
+	BapRegistryModeStrict = "strict"
+)
+
+var ErrBapNotRegistered = errors.New("bap_id is not subscribed in the registry")
+
+// ErrBapIDMismatch is returned when a signed request acts for a bap_id other than its signer.
+var ErrBapIDMismatch = errors.New("bap_id does not match the request signer")
+
+// ErrJobLeaseLost is returned when renewing the lease of a job that another worker has claimed.
+var ErrJobLeaseLost = errors.New("permissions job lease is held by another worker")
+
+// RegistryStatus returns UNREGISTERED or EXPIRED when the BAP is not currently subscribed in the
+// registry, and "" when it is. A nil BAP is unregistered, and so is one that no registry sync has
+// seen within maxAge, since BAPs that leave the registry are never removed from baps.
+func (b *Bap) RegistryStatus(now time.Time, maxAge time.Duration) string {
+	if b == nil || b.LastSeenInReg == nil || b.Status != seller.SubscriberStatusSubscribed {
+		return BapStatusUnregistered
+	}
+	if maxAge > 0 && now.Sub(*b.LastSeenInReg) > maxAge {
+		return BapStatusUnregistered
+	}
+	if (b.ValidFrom != nil && now.Before(*b.ValidFrom)) || (b.ValidUntil != nil && now.After(*b.ValidUntil)) {
+		return BapStatusExpired
+	}
+	return ""
+}
+
 type BapAccessPolicy struct {
 	SellerID       string                `gorm:"primaryKey;column:seller_id;type:text"`
 	Domain         string                `gorm:"primaryKey;column:domain;type:text"`
@@ -27,17 +82,106 @@ type BapAccessPolicy struct {
 	DecidedAt      time.Time             `gorm:"column:decided_at;type:timestamptz"`
 	ExpiresAt      *time.Time            `gorm:"column:expires_at;type:timestamptz"`
 	Reason         *string               `gorm:"column:reason;type:text"`
-	UpdatedAt      time.Time             `gorm:"column:updated_at;type:timestamptz;autoUpdateTime"`
+	// LastProbedAt is when a policy refresh last asked the seller again; it is kept across upserts.
+	LastProbedAt *time.Time `gorm:"column:last_probed_at;type:timestamptz"`
+	UpdatedAt    time.Time  `gorm:"column:updated_at;type:timestamptz;autoUpdateTime"`
 }
 
 func (BapAccessPolicy) TableName() string {
 	return "bap_access_policy"
 }
 
+const (
+	JobStatusInitiated = "INITIATED"
+	JobStatusRunning   = "RUNNING"
+	JobStatusCompleted = "COMPLETED"
+	JobStatusFailed    = "FAILED"
+	JobStatusCancelled = "CANCELLED"
+	JobStatusTimedOut  = "TIMED_OUT"
+)
+
 type PermissionsJob struct {
-	ID        uuid.UUID `json:"job_id" gorm:"type:uuid;default:gen_random_uuid();primary_key"`
-	BapID     string    `json:"bap_id" gorm:"not null"`
-	Status    string    `json:"status" gorm:"not null"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             uuid.UUID  `json:"job_id" gorm:"type:uuid;default:gen_random_uuid();primary_key"`
+	BapID          string     `json:"bap_id" gorm:"not null"`
+	Status         string     `json:"status" gorm:"not null;index"`
+	TransactionID  string     `json:"transaction_id" gorm:"index"`
+	MessageID      string     `json:"message_id"`
+	RequestPayload string     `json:"-" gorm:"column:request_payload;type:jsonb"`
+	CallbackURL    *string    `json:"callback_url,omitempty" gorm:"column:callback_url;type:text"`
+	LockedBy       *string    `json:"-" gorm:"column:locked_by;type:text"`
+	LockedUntil    *time.Time `json:"-" gorm:"column:locked_until;type:timestamptz"`
+	StartedAt      *time.Time `json:"started_at,omitempty" gorm:"column:started_at;type:timestamptz"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty" gorm:"column:finished_at;type:timestamptz"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// OnSearchResponse records a seller's asynchronous on_search callback for a permissions job
+type OnSearchResponse struct {
+	ID              uuid.UUID             `json:"id" gorm:"type:uuid;default:gen_random_uuid();primary_key"`
+	JobID           uuid.UUID             `json:"job_id" gorm:"type:uuid;index;not null"`
+	SellerID        string                `json:"seller_id" gorm:"column:seller_id;type:text;not null"`
+	Domain          string                `json:"domain" gorm:"column:domain;type:text"`
+	TransactionID   string                `json:"transaction_id" gorm:"column:transaction_id;type:text"`
+	MessageID       string                `json:"message_id" gorm:"column:message_id;type:text"`
+	Decision        seller.AccessDecision `json:"decision,omitempty" gorm:"column:decision;type:text"`
+	CatalogReceived bool                  `json:"catalog_received" gorm:"column:catalog_received"`
+	ErrorCode       *string               `json:"error_code,omitempty" gorm:"column:error_code;type:text"`
+	ErrorMessage    *string               `json:"error_message,omitempty" gorm:"column:error_message;type:text"`
+	ReceivedAt      time.Time             `json:"received_at" gorm:"column:received_at;type:timestamptz"`
+}
+
+func (OnSearchResponse) TableName() string {
+	return "on_search_responses"
+}
+
+type TargetOutcome string
+
+const (
+	TargetOutcomePending   TargetOutcome = "PENDING"
+	TargetOutcomeAck       TargetOutcome = "ACK"
+	TargetOutcomeNack      TargetOutcome = "NACK"
+	TargetOutcomeTimeout   TargetOutcome = "TIMEOUT"
+	TargetOutcomeError     TargetOutcome = "ERROR"
+	TargetOutcomeCancelled TargetOutcome = "CANCELLED"
+	// TargetOutcomeSkipped marks a seller that matched the broadcast but was not contacted;
+	// SkipReason says why.
+	TargetOutcomeSkipped TargetOutcome = "SKIPPED"
+)
+
+// PermissionsJobTarget tracks the /search request sent to a single seller for a permissions job
+type PermissionsJobTarget struct {
+	JobID        uuid.UUID             `json:"job_id" gorm:"primaryKey;type:uuid"`
+	SellerID     string                `json:"seller_id" gorm:"primaryKey;column:seller_id;type:text"`
+	Domain       string                `json:"domain" gorm:"column:domain;type:text"`
+	URL          string                `json:"url" gorm:"column:url;type:text"`
+	Outcome      TargetOutcome         `json:"outcome" gorm:"column:outcome;type:text;index"`
+	HTTPStatus   *int                  `json:"http_status,omitempty" gorm:"column:http_status"`
+	LatencyMs    *int64                `json:"latency_ms,omitempty" gorm:"column:latency_ms"`
+	Decision     seller.AccessDecision `json:"decision,omitempty" gorm:"column:decision;type:text"`
+	Error        *string               `json:"error,omitempty" gorm:"column:error;type:text"`
+	SkipReason   *string               `json:"skip_reason,omitempty" gorm:"column:skip_reason;type:text"`
+	AttemptCount int                   `json:"attempt_count" gorm:"column:attempt_count"`
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+}
+
+func (PermissionsJobTarget) TableName() string {
+	return "permissions_job_targets"
+}
+
+// WebhookDelivery logs each attempt to notify a job's callback_url
+type WebhookDelivery struct {
+	ID         uuid.UUID `json:"id" gorm:"type:uuid;default:gen_random_uuid();primary_key"`
+	JobID      uuid.UUID `json:"job_id" gorm:"type:uuid;index;not null"`
+	URL        string    `json:"url" gorm:"column:url;type:text"`
+	Attempt    int       `json:"attempt" gorm:"column:attempt"`
+	StatusCode *int      `json:"status_code,omitempty" gorm:"column:status_code"`
+	Error      *string   `json:"error,omitempty" gorm:"column:error;type:text"`
+	Delivered  bool      `json:"delivered" gorm:"column:delivered"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "permissions_job_webhook_deliveries"
 }
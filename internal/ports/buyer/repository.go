@@ -1,14 +1,51 @@
 package buyer
 
-import "github.com/google/uuid"
+import (
+	"context"
+	"time"
+
+	"adapter/internal/ports/seller"
+
+	"github.com/google/uuid"
+)
+
+// ExpiringPolicyQuery selects the policies a refresh run may probe: those expiring within
+// (ExpiresAfter, ExpiresBefore] for BAPs seen since ActiveSince, not probed since ProbedBefore.
+type ExpiringPolicyQuery struct {
+	ExpiresAfter  time.Time
+	ExpiresBefore time.Time
+	ActiveSince   time.Time
+	ProbedBefore  time.Time
+	Limit         int
+}
 
 type PermissionsRepository interface {
 	UpsertBaps(baps map[string]Bap) error
-	UpsertBapAccessPolicies(policies []BapAccessPolicy) error
+	UpsertRegistryBaps(ctx context.Context, baps []Bap) error
+	UpsertBapAccessPolicies(policies []BapAccessPolicy) ([]bool, error)
 	FindBapByID(bapID string) (*Bap, error)
 	QueryBapAccessPolicies(bapID, domain string, sellerIDs []string) ([]BapAccessPolicy, error)
 	GetBapPolicy(bapID string) (*BapAccessPolicy, error)
+	GetExpiringBapAccessPolicies(ctx context.Context, query ExpiringPolicyQuery) ([]BapAccessPolicy, error)
+	MarkBapAccessPoliciesProbed(ctx context.Context, bapID, domain string, sellerIDs []string, probedAt time.Time) error
 	CreatePermissionsJob(job *PermissionsJob) error
 	UpdatePermissionsJobStatus(jobID uuid.UUID, status string) error
+	FinishPermissionsJob(jobID uuid.UUID, status string) (bool, error)
+	ClaimPermissionsJob(workerID string, lease time.Duration) (*PermissionsJob, error)
+	RenewPermissionsJobLease(jobID uuid.UUID, workerID string, lease time.Duration) error
 	GetPermissionsJobByID(jobID uuid.UUID) (*PermissionsJob, error)
+	GetPermissionsJobByTransaction(transactionID, messageID string) (*PermissionsJob, error)
+	CreateOnSearchResponse(resp *OnSearchResponse) error
+	CreatePermissionsJobTargets(targets []PermissionsJobTarget) error
+	GetPermissionsJobTarget(jobID uuid.UUID, sellerID string) (*PermissionsJobTarget, error)
+	UpdatePermissionsJobTarget(target *PermissionsJobTarget) error
+	UpdatePermissionsJobTargetDecision(jobID uuid.UUID, sellerID string, decision seller.AccessDecision) error
+	CountPermissionsJobTargets(jobID uuid.UUID) (map[TargetOutcome]int, error)
+	GetPermissionsJobAttemptStats(jobID uuid.UUID) (attempts int, retried int, err error)
+	ListPermissionsJobTargets(jobID uuid.UUID, outcome string, limit, offset int) ([]PermissionsJobTarget, error)
+	GetPendingPermissionsJobTargets(jobID uuid.UUID) ([]PermissionsJobTarget, error)
+	GetOpenPermissionsJobSellers(bapID, domain string, sellerIDs []string) (map[string]uuid.UUID, error)
+	ClosePendingPermissionsJobTargets(jobID uuid.UUID, outcome TargetOutcome, reason string) error
+	CreateWebhookDelivery(delivery *WebhookDelivery) error
+	DeleteFinishedPermissionsJobs(ctx context.Context, finishedBefore time.Time) (int64, error)
 }
@@ -1,6 +1,11 @@
 package buyer
 
 import (
+	"context"
+	"time"
+
+	"adapter/internal/ports/seller"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -14,22 +19,96 @@ func NewBuyerRepository(db *gorm.DB) *BuyerRepository {
 	return &BuyerRepository{db: db}
 }
 
+// UpsertBaps records BAPs calling the adapter, filling unset timestamps with now. An existing row
+// has last_seen_at bumped and keeps its first_seen_at, which is only filled in for a BAP first
+// synced from the registry.
 func (r *BuyerRepository) UpsertBaps(baps map[string]Bap) error {
+	now := time.Now()
 	var bapList []Bap
 	for _, b := range baps {
+		if b.FirstSeenAt == nil {
+			b.FirstSeenAt = &now
+		}
+		if b.LastSeenAt == nil {
+			b.LastSeenAt = &now
+		}
 		bapList = append(bapList, b)
 	}
 	return r.db.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "bap_id"}},
-		DoUpdates: clause.AssignmentColumns([]string{"last_seen_at"}),
+		Columns: []clause.Column{{Name: "bap_id"}},
+		DoUpdates: append(clause.AssignmentColumns([]string{"last_seen_at"}), clause.Assignment{
+			Column: clause.Column{Name: "first_seen_at"},
+			Value:  gorm.Expr("COALESCE(baps.first_seen_at, excluded.first_seen_at)"),
+		}),
 	}).Create(&bapList).Error
 }
 
-func (r *BuyerRepository) UpsertBapAccessPolicies(policies []BapAccessPolicy) error {
-	return r.db.Clauses(clause.OnConflict{
+// UpsertRegistryBaps records BAPs listed in the registry. New rows are inserted without
+// first_seen_at and last_seen_at, and existing rows only have their registry fields refreshed,
+// so both keep tracking API traffic.
+func (r *BuyerRepository) UpsertRegistryBaps(ctx context.Context, baps []Bap) error {
+	if len(baps) == 0 {
+		return nil
+	}
+	// Rows that keep a stored validity bound need a different update list, so they are
+	// upserted in batches grouped by which bounds they keep.
+	type keep struct{ from, until bool }
+	batches := make(map[keep][]Bap)
+	for _, bap := range baps {
+		key := keep{bap.KeepValidFrom, bap.KeepValidUntil}
+		batches[key] = append(batches[key], bap)
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for key, batch := range batches {
+			columns := []string{"last_seen_in_reg", "status", "subscriber_url", "uk_id", "signing_public_key", "encr_public_key"}
+			if !key.from {
+				columns = append(columns, "valid_from")
+			}
+			if !key.until {
+				columns = append(columns, "valid_until")
+			}
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "bap_id"}},
+				DoUpdates: clause.AssignmentColumns(columns),
+			}).Create(&batch).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpsertBapAccessPolicies stores the given decisions and reports, for each policy in order,
+// whether it was written. An existing row is kept when it is a valid manual override, unless the
+// new decision is one too, and a PENDING decision never replaces a settled one that is still
+// valid, so a late ACK or a refresh probe cannot undo an on_search decision.
+func (r *BuyerRepository) UpsertBapAccessPolicies(policies []BapAccessPolicy) ([]bool, error) {
+	now := time.Now()
+	upsert := clause.OnConflict{
 		Columns:   []clause.Column{{Name: "seller_id"}, {Name: "domain"}, {Name: "bap_id"}},
 		DoUpdates: clause.AssignmentColumns([]string{"decision", "decision_source", "decided_at", "expires_at", "reason", "updated_at"}),
-	}).Create(&policies).Error
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "(bap_access_policy.decision_source IS DISTINCT FROM ? OR excluded.decision_source = ? OR bap_access_policy.expires_at <= ?)", Vars: []interface{}{seller.SourceManualOverride, seller.SourceManualOverride, now}},
+			clause.Expr{SQL: "(excluded.decision <> ? OR bap_access_policy.decision = ? OR bap_access_policy.expires_at <= ?)", Vars: []interface{}{seller.DecisionPending, seller.DecisionPending, now}},
+		}},
+	}
+	// Rows are written one at a time so that a row the conflict condition kept shows up as
+	// zero rows affected.
+	stored := make([]bool, len(policies))
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range policies {
+			result := tx.Clauses(upsert).Create(&policies[i])
+			if result.Error != nil {
+				return result.Error
+			}
+			stored[i] = result.RowsAffected > 0
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
 }
 func (r *BuyerRepository) FindBapByID(bapID string) (*Bap, error) {
 	var bap Bap
@@ -47,6 +126,36 @@ func (r *BuyerRepository) QueryBapAccessPolicies(bapID, domain string, sellerIDs
 	return policies, nil
 }
 
+// GetExpiringBapAccessPolicies returns seller-decided policies of active sellers matching query,
+// soonest first. Pending policies are only returned once expired, since their probe is still
+// awaiting an on_search.
+func (r *BuyerRepository) GetExpiringBapAccessPolicies(ctx context.Context, query ExpiringPolicyQuery) ([]BapAccessPolicy, error) {
+	var policies []BapAccessPolicy
+	err := r.db.WithContext(ctx).
+		Joins("JOIN baps ON baps.bap_id = bap_access_policy.bap_id").
+		Joins("JOIN sellers ON sellers.seller_id = bap_access_policy.seller_id AND sellers.domain = bap_access_policy.domain AND sellers.active").
+		Where("baps.last_seen_at >= ?", query.ActiveSince).
+		Where("bap_access_policy.expires_at > ? AND bap_access_policy.expires_at <= ?", query.ExpiresAfter, query.ExpiresBefore).
+		Where("bap_access_policy.last_probed_at IS NULL OR bap_access_policy.last_probed_at < ?", query.ProbedBefore).
+		Where("bap_access_policy.decision_source <> ?", seller.SourceManualOverride).
+		Where("bap_access_policy.decision <> ? OR bap_access_policy.expires_at <= ?", seller.DecisionPending, time.Now()).
+		Order("bap_access_policy.expires_at ASC").
+		Limit(query.Limit).
+		Find(&policies).Error
+	if err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// MarkBapAccessPoliciesProbed records that a refresh asked the sellers again, so that a seller
+// that does not answer is not probed on every run.
+func (r *BuyerRepository) MarkBapAccessPoliciesProbed(ctx context.Context, bapID, domain string, sellerIDs []string, probedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&BapAccessPolicy{}).
+		Where("bap_id = ? AND domain = ? AND seller_id IN ?", bapID, domain, sellerIDs).
+		UpdateColumn("last_probed_at", probedAt).Error
+}
+
 func (r *BuyerRepository) GetBapPolicy(bapID string) (*BapAccessPolicy, error) {
 	var policy BapAccessPolicy
 	if err := r.db.Where("bap_id = ?", bapID).First(&policy).Error; err != nil {
@@ -66,6 +175,74 @@ func (r *BuyerRepository) UpdatePermissionsJobStatus(jobID uuid.UUID, status str
 	return r.db.Model(&PermissionsJob{}).Where("id = ?", jobID).Update("status", status).Error
 }
 
+// FinishPermissionsJob moves a queued or running job to a terminal status. It reports false when
+// the job had already finished, so a late completion never overwrites a cancellation.
+func (r *BuyerRepository) FinishPermissionsJob(jobID uuid.UUID, status string) (bool, error) {
+	result := r.db.Model(&PermissionsJob{}).
+		Where("id = ? AND status IN ?", jobID, []string{JobStatusInitiated, JobStatusRunning}).
+		Updates(map[string]interface{}{
+			"status":       status,
+			"finished_at":  time.Now(),
+			"locked_by":    nil,
+			"locked_until": nil,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ClaimPermissionsJob locks the oldest queued job, or a running job whose lease has lapsed,
+// for the given worker. It returns nil when there is nothing to pick up.
+func (r *BuyerRepository) ClaimPermissionsJob(workerID string, lease time.Duration) (*PermissionsJob, error) {
+	var claimed *PermissionsJob
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var job PermissionsJob
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? OR (status = ? AND (locked_until IS NULL OR locked_until < ?))", JobStatusInitiated, JobStatusRunning, now).
+			Order("created_at").
+			First(&job).Error
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil
+			}
+			return err
+		}
+
+		lockedUntil := now.Add(lease)
+		updates := map[string]interface{}{
+			"status":       JobStatusRunning,
+			"locked_by":    workerID,
+			"locked_until": lockedUntil,
+		}
+		if job.StartedAt == nil {
+			updates["started_at"] = now
+			job.StartedAt = &now
+		}
+		if err := tx.Model(&PermissionsJob{}).Where("id = ?", job.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		job.Status = JobStatusRunning
+		job.LockedBy = &workerID
+		job.LockedUntil = &lockedUntil
+		claimed = &job
+		return nil
+	})
+	return claimed, err
+}
+
+func (r *BuyerRepository) RenewPermissionsJobLease(jobID uuid.UUID, workerID string, lease time.Duration) error {
+	result := r.db.Model(&PermissionsJob{}).
+		Where("id = ? AND locked_by = ?", jobID, workerID).
+		Update("locked_until", time.Now().Add(lease))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrJobLeaseLost
+	}
+	return nil
+}
+
 func (r *BuyerRepository) GetPermissionsJobByID(jobID uuid.UUID) (*PermissionsJob, error) {
 	var job PermissionsJob
 	if err := r.db.Where("id = ?", jobID).First(&job).Error; err != nil {
@@ -73,3 +250,184 @@ func (r *BuyerRepository) GetPermissionsJobByID(jobID uuid.UUID) (*PermissionsJo
 	}
 	return &job, nil
 }
+
+func (r *BuyerRepository) GetPermissionsJobByTransaction(transactionID, messageID string) (*PermissionsJob, error) {
+	var job PermissionsJob
+	query := r.db.Where("transaction_id = ?", transactionID)
+	if messageID != "" {
+		query = query.Where("message_id = ?", messageID)
+	}
+	if err := query.Order("created_at DESC").First(&job).Error; err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *BuyerRepository) CreateOnSearchResponse(resp *OnSearchResponse) error {
+	return r.db.Create(resp).Error
+}
+
+func (r *BuyerRepository) CreatePermissionsJobTargets(targets []PermissionsJobTarget) error {
+	if len(targets) == 0 {
+		return nil
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&targets, 500).Error
+}
+
+func (r *BuyerRepository) GetPermissionsJobTarget(jobID uuid.UUID, sellerID string) (*PermissionsJobTarget, error) {
+	var target PermissionsJobTarget
+	if err := r.db.Where("job_id = ? AND seller_id = ?", jobID, sellerID).First(&target).Error; err != nil {
+		return nil, err
+	}
+	return &target, nil
+}
+
+// UpdatePermissionsJobTarget records a target's outcome. A target handed back as PENDING, by a
+// worker that lost the job or is shutting down, is only written while the row is still PENDING,
+// so it never undoes an outcome recorded by the worker that took the job over.
+func (r *BuyerRepository) UpdatePermissionsJobTarget(target *PermissionsJobTarget) error {
+	query := r.db.Model(&PermissionsJobTarget{}).
+		Where("job_id = ? AND seller_id = ?", target.JobID, target.SellerID)
+	if target.Outcome == TargetOutcomePending {
+		query = query.Where("outcome = ?", TargetOutcomePending)
+	}
+	return query.
+		Updates(map[string]interface{}{
+			"url":         target.URL,
+			"outcome":     target.Outcome,
+			"http_status": target.HTTPStatus,
+			"latency_ms":  target.LatencyMs,
+			// A decision from an on_search that overtook this outcome is kept over PENDING.
+			"decision":      gorm.Expr("CASE WHEN ? = ? AND decision NOT IN ('', ?) THEN decision ELSE ? END", target.Decision, seller.DecisionPending, seller.DecisionPending, target.Decision),
+			"error":         target.Error,
+			"attempt_count": target.AttemptCount,
+		}).Error
+}
+
+func (r *BuyerRepository) UpdatePermissionsJobTargetDecision(jobID uuid.UUID, sellerID string, decision seller.AccessDecision) error {
+	return r.db.Model(&PermissionsJobTarget{}).
+		Where("job_id = ? AND seller_id = ?", jobID, sellerID).
+		Update("decision", decision).Error
+}
+
+func (r *BuyerRepository) CountPermissionsJobTargets(jobID uuid.UUID) (map[TargetOutcome]int, error) {
+	var rows []struct {
+		Outcome TargetOutcome
+		Count   int
+	}
+	if err := r.db.Model(&PermissionsJobTarget{}).
+		Select("outcome, COUNT(*) AS count").
+		Where("job_id = ?", jobID).
+		Group("outcome").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	counts := make(map[TargetOutcome]int, len(rows))
+	for _, row := range rows {
+		counts[row.Outcome] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *BuyerRepository) GetPermissionsJobAttemptStats(jobID uuid.UUID) (int, int, error) {
+	var stats struct {
+		Attempts int
+		Retried  int
+	}
+	if err := r.db.Model(&PermissionsJobTarget{}).
+		Select("COALESCE(SUM(attempt_count), 0) AS attempts, COUNT(*) FILTER (WHERE attempt_count > 1) AS retried").
+		Where("job_id = ?", jobID).
+		Scan(&stats).Error; err != nil {
+		return 0, 0, err
+	}
+	return stats.Attempts, stats.Retried, nil
+}
+
+func (r *BuyerRepository) ListPermissionsJobTargets(jobID uuid.UUID, outcome string, limit, offset int) ([]PermissionsJobTarget, error) {
+	var targets []PermissionsJobTarget
+	query := r.db.Where("job_id = ?", jobID)
+	if outcome != "" {
+		query = query.Where("outcome = ?", outcome)
+	}
+	// Fetch one extra record so callers can tell whether there is another page.
+	if err := query.Order("seller_id").Limit(limit + 1).Offset(offset).Find(&targets).Error; err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+func (r *BuyerRepository) GetPendingPermissionsJobTargets(jobID uuid.UUID) ([]PermissionsJobTarget, error) {
+	var targets []PermissionsJobTarget
+	if err := r.db.Where("job_id = ? AND outcome = ?", jobID, TargetOutcomePending).Find(&targets).Error; err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// GetOpenPermissionsJobSellers maps each of sellerIDs that a queued or running job of bapID is
+// already contacting in domain to that job. Jobs whose targets have not been created yet are
+// matched on the seller_ids of their request.
+func (r *BuyerRepository) GetOpenPermissionsJobSellers(bapID, domain string, sellerIDs []string) (map[string]uuid.UUID, error) {
+	var rows []struct {
+		SellerID string
+		JobID    uuid.UUID
+	}
+	openStatuses := []string{JobStatusInitiated, JobStatusRunning}
+	err := r.db.Raw(`
+		SELECT t.seller_id, t.job_id
+		FROM permissions_job_targets t
+		JOIN permissions_jobs j ON j.id = t.job_id
+		WHERE j.bap_id = ? AND j.status IN ? AND t.domain = ? AND t.seller_id IN ?
+		UNION
+		SELECT s.seller_id, j.id AS job_id
+		FROM permissions_jobs j
+		CROSS JOIN LATERAL jsonb_array_elements_text(COALESCE(j.request_payload->'seller_ids', '[]'::jsonb)) AS s(seller_id)
+		WHERE j.bap_id = ? AND j.status IN ? AND j.request_payload->'search_payload'->'context'->>'domain' = ?
+			AND s.seller_id IN ?
+			AND NOT EXISTS (SELECT 1 FROM permissions_job_targets t WHERE t.job_id = j.id)`,
+		bapID, openStatuses, domain, sellerIDs,
+		bapID, openStatuses, domain, sellerIDs).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	open := make(map[string]uuid.UUID, len(rows))
+	for _, row := range rows {
+		open[row.SellerID] = row.JobID
+	}
+	return open, nil
+}
+
+func (r *BuyerRepository) ClosePendingPermissionsJobTargets(jobID uuid.UUID, outcome TargetOutcome, reason string) error {
+	return r.db.Model(&PermissionsJobTarget{}).
+		Where("job_id = ? AND outcome = ?", jobID, TargetOutcomePending).
+		Updates(map[string]interface{}{"outcome": outcome, "error": reason}).Error
+}
+
+func (r *BuyerRepository) CreateWebhookDelivery(delivery *WebhookDelivery) error {
+	return r.db.Create(delivery).Error
+}
+
+// DeleteFinishedPermissionsJobs removes jobs that finished before the cutoff together with their
+// targets, on_search responses and webhook deliveries, returning the number of jobs deleted.
+func (r *BuyerRepository) DeleteFinishedPermissionsJobs(ctx context.Context, finishedBefore time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		jobIDs := tx.Model(&PermissionsJob{}).Select("id").
+			Where("finished_at IS NOT NULL AND finished_at < ?", finishedBefore)
+
+		for _, model := range []interface{}{&PermissionsJobTarget{}, &OnSearchResponse{}, &WebhookDelivery{}} {
+			if err := tx.Where("job_id IN (?)", jobIDs).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		result := tx.Where("finished_at IS NOT NULL AND finished_at < ?", finishedBefore).Delete(&PermissionsJob{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		return nil
+	})
+	return deleted, err
+}
@@ -0,0 +1,278 @@
+//go:build integration
+
+package buyer_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"adapter/internal/ports/broadcast"
+	buyerPorts "adapter/internal/ports/buyer"
+	sellerPorts "adapter/internal/ports/seller"
+	"adapter/internal/testharness"
+
+	"gorm.io/gorm"
+)
+
+const bapID = "buyer.example"
+
+func newBuyerRepository(t *testing.T) (*buyerPorts.BuyerRepository, *gorm.DB) {
+	t.Helper()
+	db := testharness.NewPostgres(t, &sellerPorts.Seller{}, &buyerPorts.Bap{}, &buyerPorts.BapAccessPolicy{},
+		&buyerPorts.PermissionsJob{}, &buyerPorts.PermissionsJobTarget{})
+	return buyerPorts.NewBuyerRepository(db), db
+}
+
+func policy(sellerID string, decision sellerPorts.AccessDecision, source sellerPorts.DecisionSource, expiresAt time.Time) buyerPorts.BapAccessPolicy {
+	return buyerPorts.BapAccessPolicy{
+		SellerID: sellerID, Domain: testharness.TestDomain, BapID: bapID,
+		Decision: decision, DecisionSource: source, DecidedAt: time.Now(), ExpiresAt: &expiresAt,
+	}
+}
+
+func storedPolicies(t *testing.T, repo *buyerPorts.BuyerRepository, sellerIDs ...string) map[string]buyerPorts.BapAccessPolicy {
+	t.Helper()
+	policies, err := repo.QueryBapAccessPolicies(bapID, testharness.TestDomain, sellerIDs)
+	if err != nil {
+		t.Fatalf("QueryBapAccessPolicies: %v", err)
+	}
+	bySeller := make(map[string]buyerPorts.BapAccessPolicy, len(policies))
+	for _, p := range policies {
+		bySeller[p.SellerID] = p
+	}
+	return bySeller
+}
+
+func TestUpsertBapAccessPoliciesConflictRules(t *testing.T) {
+	repo, _ := newBuyerRepository(t)
+	now := time.Now()
+
+	_, err := repo.UpsertBapAccessPolicies([]buyerPorts.BapAccessPolicy{
+		policy("settled", sellerPorts.DecisionAllowed, sellerPorts.SourceSellerOnSearch, now.Add(time.Hour)),
+		policy("expired", sellerPorts.DecisionAllowed, sellerPorts.SourceSellerOnSearch, now.Add(-time.Hour)),
+		policy("pending", sellerPorts.DecisionPending, sellerPorts.SourceSellerAck, now.Add(time.Hour)),
+		policy("manual", sellerPorts.DecisionDenied, sellerPorts.SourceManualOverride, now.Add(time.Hour)),
+		policy("remanual", sellerPorts.DecisionDenied, sellerPorts.SourceManualOverride, now.Add(time.Hour)),
+		policy("expired-manual", sellerPorts.DecisionDenied, sellerPorts.SourceManualOverride, now.Add(-time.Hour)),
+	})
+	if err != nil {
+		t.Fatalf("UpsertBapAccessPolicies: %v", err)
+	}
+	probedAt := now.Add(-time.Minute).UTC().Truncate(time.Second)
+	if err := repo.MarkBapAccessPoliciesProbed(context.Background(), bapID, testharness.TestDomain, []string{"expired"}, probedAt); err != nil {
+		t.Fatalf("MarkBapAccessPoliciesProbed: %v", err)
+	}
+
+	written, err := repo.UpsertBapAccessPolicies([]buyerPorts.BapAccessPolicy{
+		policy("settled", sellerPorts.DecisionPending, sellerPorts.SourceSellerAck, now.Add(2*time.Hour)),
+		policy("expired", sellerPorts.DecisionPending, sellerPorts.SourceSellerAck, now.Add(2*time.Hour)),
+		policy("pending", sellerPorts.DecisionAllowed, sellerPorts.SourceSellerOnSearch, now.Add(2*time.Hour)),
+		policy("manual", sellerPorts.DecisionAllowed, sellerPorts.SourceSellerOnSearch, now.Add(2*time.Hour)),
+		policy("remanual", sellerPorts.DecisionAllowed, sellerPorts.SourceManualOverride, now.Add(2*time.Hour)),
+		policy("new", sellerPorts.DecisionPending, sellerPorts.SourceSellerAck, now.Add(2*time.Hour)),
+		policy("expired-manual", sellerPorts.DecisionAllowed, sellerPorts.SourceSellerOnSearch, now.Add(2*time.Hour)),
+	})
+	if err != nil {
+		t.Fatalf("UpsertBapAccessPolicies: %v", err)
+	}
+	if want := []bool{false, true, true, false, true, true, true}; !reflect.DeepEqual(written, want) {
+		t.Errorf("written = %v, want %v", written, want)
+	}
+
+	stored := storedPolicies(t, repo, "settled", "expired", "pending", "manual", "remanual", "new", "expired-manual")
+	want := map[string]sellerPorts.AccessDecision{
+		"settled":        sellerPorts.DecisionAllowed, // a PENDING ACK does not undo a valid decision
+		"expired":        sellerPorts.DecisionPending, // but replaces an expired one
+		"pending":        sellerPorts.DecisionAllowed,
+		"manual":         sellerPorts.DecisionDenied, // seller decisions never replace a valid manual override
+		"remanual":       sellerPorts.DecisionAllowed,
+		"new":            sellerPorts.DecisionPending,
+		"expired-manual": sellerPorts.DecisionAllowed, // but do replace an expired one
+	}
+	for sellerID, decision := range want {
+		if got := stored[sellerID]; got.Decision != decision {
+			t.Errorf("%s: decision = %s, want %s", sellerID, got.Decision, decision)
+		}
+	}
+	if got := stored["expired"].LastProbedAt; got == nil || !got.Equal(probedAt) {
+		t.Errorf("last_probed_at = %v, want it kept at %v", got, probedAt)
+	}
+}
+
+func TestGetExpiringBapAccessPolicies(t *testing.T) {
+	repo, db := newBuyerRepository(t)
+	now := time.Now()
+
+	var sellers []sellerPorts.Seller
+	for _, id := range []string{"soon", "sooner", "later", "long-expired", "pending", "pending-expired", "manual", "probed", "probed-long-ago"} {
+		sellers = append(sellers, sellerPorts.Seller{SellerID: id, Domain: testharness.TestDomain, Active: true, RegistryRaw: "{}", LastSeenInReg: now})
+	}
+	sellers = append(sellers, sellerPorts.Seller{SellerID: "inactive", Domain: testharness.TestDomain, Active: false, RegistryRaw: "{}", LastSeenInReg: now})
+	if err := sellerPorts.NewSellerRepository(db).InsertSellers(sellers); err != nil {
+		t.Fatalf("InsertSellers: %v", err)
+	}
+	firstSeen, idleSince := now.Add(-30*24*time.Hour), now.Add(-10*24*time.Hour)
+	if err := repo.UpsertBaps(map[string]buyerPorts.Bap{
+		bapID:          {BapID: bapID, FirstSeenAt: &now, LastSeenAt: &now},
+		"idle.example": {BapID: "idle.example", FirstSeenAt: &firstSeen, LastSeenAt: &idleSince},
+	}); err != nil {
+		t.Fatalf("UpsertBaps: %v", err)
+	}
+
+	idle := policy("soon", sellerPorts.DecisionAllowed, sellerPorts.SourceSellerOnSearch, now.Add(20*time.Minute))
+	idle.BapID = "idle.example"
+	if _, err := repo.UpsertBapAccessPolicies([]buyerPorts.BapAccessPolicy{
+		policy("soon", sellerPorts.DecisionAllowed, sellerPorts.SourceSellerOnSearch, now.Add(30*time.Minute)),
+		policy("sooner", sellerPorts.DecisionDenied, sellerPorts.SourceSellerNack, now.Add(10*time.Minute)),
+		policy("later", sellerPorts.DecisionAllowed, sellerPorts.SourceSellerOnSearch, now.Add(3*time.Hour)),
+		policy("long-expired", sellerPorts.DecisionAllowed, sellerPorts.SourceSellerOnSearch, now.Add(-48*time.Hour)),
+		policy("pending", sellerPorts.DecisionPending, sellerPorts.SourceSellerAck, now.Add(20*time.Minute)),
+		policy("pending-expired", sellerPorts.DecisionPending, sellerPorts.SourceSellerAck, now.Add(-5*time.Minute)),
+		policy("manual", sellerPorts.DecisionDenied, sellerPorts.SourceManualOverride, now.Add(15*time.Minute)),
+		policy("probed", sellerPorts.DecisionAllowed, sellerPorts.SourceSellerOnSearch, now.Add(15*time.Minute)),
+		policy("probed-long-ago", sellerPorts.DecisionAllowed, sellerPorts.SourceSellerOnSearch, now.Add(25*time.Minute)),
+		policy("inactive", sellerPorts.DecisionAllowed, sellerPorts.SourceSellerOnSearch, now.Add(15*time.Minute)),
+		idle,
+	}); err != nil {
+		t.Fatalf("UpsertBapAccessPolicies: %v", err)
+	}
+	if err := repo.MarkBapAccessPoliciesProbed(context.Background(), bapID, testharness.TestDomain, []string{"probed"}, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("MarkBapAccessPoliciesProbed: %v", err)
+	}
+	if err := repo.MarkBapAccessPoliciesProbed(context.Background(), bapID, testharness.TestDomain, []string{"probed-long-ago"}, now.Add(-3*time.Hour)); err != nil {
+		t.Fatalf("MarkBapAccessPoliciesProbed: %v", err)
+	}
+
+	query := buyerPorts.ExpiringPolicyQuery{
+		ExpiresAfter:  now.Add(-24 * time.Hour),
+		ExpiresBefore: now.Add(time.Hour),
+		ActiveSince:   now.Add(-72 * time.Hour),
+		ProbedBefore:  now.Add(-2 * time.Hour),
+		Limit:         10,
+	}
+	policies, err := repo.GetExpiringBapAccessPolicies(context.Background(), query)
+	if err != nil {
+		t.Fatalf("GetExpiringBapAccessPolicies: %v", err)
+	}
+	var got []string
+	for _, p := range policies {
+		got = append(got, p.BapID+"/"+p.SellerID)
+	}
+	want := []string{bapID + "/pending-expired", bapID + "/sooner", bapID + "/probed-long-ago", bapID + "/soon"}
+	if len(got) != len(want) {
+		t.Fatalf("expiring policies = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expiring policies = %v, want %v", got, want)
+		}
+	}
+
+	query.Limit = 2
+	if policies, err := repo.GetExpiringBapAccessPolicies(context.Background(), query); err != nil || len(policies) != 2 || policies[0].SellerID != "pending-expired" {
+		t.Errorf("limited query = %+v, %v", policies, err)
+	}
+}
+
+func TestUpsertRegistryBapsKeepsFlaggedValidity(t *testing.T) {
+	repo, _ := newBuyerRepository(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	from, until := now.Add(-time.Hour), now.Add(time.Hour)
+
+	if err := repo.UpsertRegistryBaps(context.Background(), []buyerPorts.Bap{{BapID: bapID, LastSeenInReg: &now, Status: "SUBSCRIBED", ValidFrom: &from, ValidUntil: &until}}); err != nil {
+		t.Fatalf("UpsertRegistryBaps: %v", err)
+	}
+	if err := repo.UpsertRegistryBaps(context.Background(), []buyerPorts.Bap{
+		{BapID: bapID, LastSeenInReg: &now, Status: "INITIATED", KeepValidUntil: true},
+		{BapID: "other.example", LastSeenInReg: &now, Status: "SUBSCRIBED", KeepValidFrom: true},
+	}); err != nil {
+		t.Fatalf("UpsertRegistryBaps: %v", err)
+	}
+
+	stored, err := repo.FindBapByID(bapID)
+	if err != nil {
+		t.Fatalf("FindBapByID: %v", err)
+	}
+	if stored.Status != "INITIATED" || stored.ValidFrom != nil || stored.ValidUntil == nil || !stored.ValidUntil.Equal(until) {
+		t.Errorf("stored BAP = %+v, want valid_from cleared and valid_until kept", stored)
+	}
+	if other, err := repo.FindBapByID("other.example"); err != nil || other.ValidFrom != nil || other.FirstSeenAt != nil || other.LastSeenAt != nil {
+		t.Errorf("new BAP = %+v, %v, want it inserted without validity or seen timestamps", other, err)
+	}
+
+	// Its first call to the adapter fills in first_seen_at.
+	if err := repo.UpsertBaps(map[string]buyerPorts.Bap{"other.example": {BapID: "other.example"}}); err != nil {
+		t.Fatalf("UpsertBaps: %v", err)
+	}
+	if other, err := repo.FindBapByID("other.example"); err != nil || other.FirstSeenAt == nil || other.LastSeenAt == nil {
+		t.Errorf("BAP after its first call = %+v, %v, want first_seen_at and last_seen_at set", other, err)
+	}
+}
+
+func TestGetOpenPermissionsJobSellers(t *testing.T) {
+	repo, _ := newBuyerRepository(t)
+
+	newJob := func(status string, sellerIDs ...string) *buyerPorts.PermissionsJob {
+		t.Helper()
+		payload, _ := json.Marshal(broadcast.BroadcastRequest{
+			SearchPayload: &broadcast.SearchPayload{Context: &broadcast.Context{Domain: testharness.TestDomain, BapID: bapID}},
+			SellerIDs:     sellerIDs,
+		})
+		job := &buyerPorts.PermissionsJob{BapID: bapID, Status: status, RequestPayload: string(payload)}
+		if err := repo.CreatePermissionsJob(job); err != nil {
+			t.Fatalf("CreatePermissionsJob: %v", err)
+		}
+		return job
+	}
+	queued := newJob(buyerPorts.JobStatusInitiated, "queued-1", "queued-2")
+	running := newJob(buyerPorts.JobStatusRunning, "running", "unlisted")
+	if err := repo.CreatePermissionsJobTargets([]buyerPorts.PermissionsJobTarget{
+		{JobID: running.ID, SellerID: "running", Domain: testharness.TestDomain, Outcome: buyerPorts.TargetOutcomePending},
+	}); err != nil {
+		t.Fatalf("CreatePermissionsJobTargets: %v", err)
+	}
+	newJob(buyerPorts.JobStatusCompleted, "finished")
+
+	open, err := repo.GetOpenPermissionsJobSellers(bapID, testharness.TestDomain, []string{"queued-2", "running", "unlisted", "finished", "unknown"})
+	if err != nil {
+		t.Fatalf("GetOpenPermissionsJobSellers: %v", err)
+	}
+	if len(open) != 2 || open["queued-2"] != queued.ID || open["running"] != running.ID {
+		t.Errorf("open sellers = %v, want queued-2 in %s and running in %s", open, queued.ID, running.ID)
+	}
+}
+
+func TestUpdatePermissionsJobTargetKeepsSettledOutcome(t *testing.T) {
+	repo, _ := newBuyerRepository(t)
+
+	job := &buyerPorts.PermissionsJob{BapID: bapID, Status: buyerPorts.JobStatusRunning}
+	if err := repo.CreatePermissionsJob(job); err != nil {
+		t.Fatalf("CreatePermissionsJob: %v", err)
+	}
+	if err := repo.CreatePermissionsJobTargets([]buyerPorts.PermissionsJobTarget{
+		{JobID: job.ID, SellerID: "seller", Domain: testharness.TestDomain, Outcome: buyerPorts.TargetOutcomePending},
+	}); err != nil {
+		t.Fatalf("CreatePermissionsJobTargets: %v", err)
+	}
+
+	// The worker that took the job over records an ACK before the old worker hands the target back.
+	acked := buyerPorts.PermissionsJobTarget{JobID: job.ID, SellerID: "seller", Outcome: buyerPorts.TargetOutcomeAck, AttemptCount: 1}
+	if err := repo.UpdatePermissionsJobTarget(&acked); err != nil {
+		t.Fatalf("UpdatePermissionsJobTarget: %v", err)
+	}
+	handedBack := buyerPorts.PermissionsJobTarget{JobID: job.ID, SellerID: "seller", Outcome: buyerPorts.TargetOutcomePending, AttemptCount: 2}
+	if err := repo.UpdatePermissionsJobTarget(&handedBack); err != nil {
+		t.Fatalf("UpdatePermissionsJobTarget: %v", err)
+	}
+
+	stored, err := repo.GetPermissionsJobTarget(job.ID, "seller")
+	if err != nil {
+		t.Fatalf("GetPermissionsJobTarget: %v", err)
+	}
+	if stored.Outcome != buyerPorts.TargetOutcomeAck || stored.AttemptCount != 1 {
+		t.Errorf("target = %s after %d attempts, want the ACK kept", stored.Outcome, stored.AttemptCount)
+	}
+}
@@ -70,21 +70,83 @@ type SellerPermissionDetail struct {
 }
 
 // SellerRegistrySyncRequest defines the request body for the /v1/internal/registry-sync API
+// Countries, City and Types override the configured lookup for this run.
 type SellerRegistrySyncRequest struct {
-	Domains []string `json:"domains"`
+	Domains   []string `json:"domains"`
+	Countries []string `json:"countries,omitempty"`
+	City      string   `json:"city,omitempty"`
+	Types     []string `json:"types,omitempty"`
 }
 
 // SellerDomainSyncSummary provides a summary of the sync operation for a single domain
 type SellerDomainSyncSummary struct {
-	Domain                 string `json:"domain"`
-	NewSellers             int    `json:"new_sellers"`
-	UpdatedSellers         int    `json:"updated_sellers"`
-	DeactivatedSellers     int    `json:"deactivated_sellers"`
-	TotalSellersInRegistry int    `json:"total_sellers_in_registry"`
+	Domain                 string  `json:"domain"`
+	Status                 string  `json:"status"`
+	Error                  *string `json:"error,omitempty"`
+	NewSellers             int     `json:"new_sellers"`
+	UpdatedSellers         int     `json:"updated_sellers"`
+	ReactivatedSellers     int     `json:"reactivated_sellers"`
+	UnchangedSellers       int     `json:"unchanged_sellers"`
+	InvalidURLSellers      int     `json:"invalid_url_sellers"`
+	DeactivatedSellers     int     `json:"deactivated_sellers"`
+	TotalSellersInRegistry int     `json:"total_sellers_in_registry"`
+	MalformedRecords       int     `json:"malformed_records"`
 }
 
 // SellerRegistrySyncResponse defines the response body for the /v1/internal/registry-sync API
 type SellerRegistrySyncResponse struct {
-	Domains []SellerDomainSyncSummary `json:"domains"`
-	RunAt   string                    `json:"run_at"`
+	RunID            string                    `json:"run_id,omitempty"`
+	Status           string                    `json:"status"`
+	Domains          []SellerDomainSyncSummary `json:"domains"`
+	Baps             []BapDomainSyncSummary    `json:"baps,omitempty"`
+	MalformedRecords int                       `json:"malformed_records"`
+	RunAt            string                    `json:"run_at"`
+}
+
+// BapDomainSyncSummary provides a summary of the BAPs synced into the baps table for a single domain
+type BapDomainSyncSummary struct {
+	Domain           string  `json:"domain"`
+	Status           string  `json:"status"`
+	Error            *string `json:"error,omitempty"`
+	TotalInRegistry  int     `json:"total_in_registry"`
+	Upserted         int     `json:"upserted"`
+	MalformedRecords int     `json:"malformed_records"`
+}
+
+// RegistrySyncRunsResponse defines the response body for the /v1/internal/registry-sync/runs API
+type RegistrySyncRunsResponse struct {
+	Runs []RegistrySyncRun `json:"runs"`
+	Page PageInfo          `json:"page"`
+}
+
+// RegistrySyncRunChangesResponse defines the response body for the
+// /v1/internal/registry-sync/runs/:id/sellers API
+type RegistrySyncRunChangesResponse struct {
+	RunID   string         `json:"run_id"`
+	Trigger string         `json:"trigger"`
+	Changes []SellerChange `json:"changes"`
+	Page    PageInfo       `json:"page"`
+}
+
+// SellerHistoryResponse defines the response body for the /v1/sellers/:seller_id/history API
+type SellerHistoryResponse struct {
+	SellerID string         `json:"seller_id"`
+	Domain   string         `json:"domain,omitempty"`
+	Changes  []SellerChange `json:"changes"`
+	Page     PageInfo       `json:"page"`
+}
+
+// InvalidURLSeller describes a seller whose subscriber_url cannot be used
+type InvalidURLSeller struct {
+	SellerID      string `json:"seller_id"`
+	Domain        string `json:"domain"`
+	SubscriberURL string `json:"subscriber_url"`
+	Error         string `json:"error"`
+}
+
+// InvalidURLSellersResponse defines the response body for the /v1/internal/sellers/invalid-urls API
+type InvalidURLSellersResponse struct {
+	Domain  string             `json:"domain,omitempty"`
+	Sellers []InvalidURLSeller `json:"sellers"`
+	Page    PageInfo           `json:"page"`
 }
@@ -1,6 +1,10 @@
 package seller
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type AccessDecision string
 type DecisionSource string
@@ -9,29 +13,43 @@ const (
 	DecisionAllowed       AccessDecision = "ALLOWED"
 	DecisionDenied        AccessDecision = "DENIED"
 	DecisionErrorOccurred AccessDecision = "ERROR_OCCURRED"
+	DecisionPending       AccessDecision = "PENDING"
+	// DecisionExpired is only reported by permission queries; it is never stored.
+	DecisionExpired AccessDecision = "EXPIRED"
 )
 
 const (
 	SourceSellerAck      DecisionSource = "SELLER_ACK"
 	SourceSellerNack     DecisionSource = "SELLER_NACK"
 	SourceManualOverride DecisionSource = "MANUAL_OVERRIDE"
+	SourceSellerOnSearch DecisionSource = "SELLER_ON_SEARCH"
 )
 
+// SubscriberStatusSubscribed is the registry status of a subscriber that may take part in transactions
+const SubscriberStatusSubscribed = "SUBSCRIBED"
+
+// Seller is a BPP subscription synced from the registry. URLError explains why SubscriberURL
+// cannot be used to reach the seller and is nil when it can.
 type Seller struct {
-	SellerID      string    `json:"seller_id" gorm:"primaryKey;column:seller_id;type:text"`
-	Domain        string    `json:"domain" gorm:"primaryKey;column:domain;type:text"`
-	Status        string    `json:"status" gorm:"column:status;type:text"`
-	Type          string    `json:"type" gorm:"column:type;type:text"`
-	SubscriberURL string    `json:"subscriber_url" gorm:"column:subscriber_url;type:text"`
-	Country       string    `json:"country" gorm:"column:country;type:text"`
-	City          string    `json:"city" gorm:"column:city;type:text"`
-	ValidFrom     time.Time `json:"valid_from" gorm:"column:valid_from;type:timestamptz"`
-	ValidUntil    time.Time `json:"valid_until" gorm:"column:valid_until;type:timestamptz"`
-	Active        bool      `json:"active" gorm:"column:active;type:boolean"`
-	RegistryRaw   string    `json:"registry_raw" gorm:"column:registry_raw;type:jsonb"`
-	LastSeenInReg time.Time `json:"last_seen_in_reg" gorm:"column:last_seen_in_reg;type:timestamptz"`
-	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	SellerID      string     `json:"seller_id" gorm:"primaryKey;column:seller_id;type:text"`
+	Domain        string     `json:"domain" gorm:"primaryKey;column:domain;type:text"`
+	Status        string     `json:"status" gorm:"column:status;type:text"`
+	Type          string     `json:"type" gorm:"column:type;type:text"`
+	SubscriberURL string     `json:"subscriber_url" gorm:"column:subscriber_url;type:text"`
+	URLError      *string    `json:"url_error,omitempty" gorm:"column:url_error;type:text"`
+	BrID          string     `json:"br_id" gorm:"column:br_id;type:text"`
+	UkID          string     `json:"ukId" gorm:"column:uk_id;type:text"`
+	SigningKey    string     `json:"signing_public_key" gorm:"column:signing_public_key;type:text"`
+	EncryptionKey string     `json:"encr_public_key" gorm:"column:encr_public_key;type:text"`
+	Country       string     `json:"country" gorm:"column:country;type:text"`
+	City          string     `json:"city" gorm:"column:city;type:text"`
+	ValidFrom     *time.Time `json:"valid_from" gorm:"column:valid_from;type:timestamptz"`
+	ValidUntil    *time.Time `json:"valid_until" gorm:"column:valid_until;type:timestamptz"`
+	Active        bool       `json:"active" gorm:"column:active;type:boolean"`
+	RegistryRaw   string     `json:"registry_raw" gorm:"column:registry_raw;type:jsonb"`
+	LastSeenInReg time.Time  `json:"last_seen_in_reg" gorm:"column:last_seen_in_reg;type:timestamptz"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
 func (Seller) TableName() string {
@@ -61,3 +79,68 @@ type SellerCatalogState struct {
 func (SellerCatalogState) TableName() string {
 	return "seller_catalog_state"
 }
+
+// Registry sync statuses, per domain and for the run as a whole. A domain is FAILED when
+// nothing was reconciled; a run is PARTIAL when some but not all of its domains failed.
+const (
+	SyncStatusOK      = "OK"
+	SyncStatusPartial = "PARTIAL"
+	SyncStatusFailed  = "FAILED"
+)
+
+const (
+	RegistrySyncTriggerCron = "cron"
+	RegistrySyncTriggerAPI  = "api"
+)
+
+// RegistrySyncRun records one execution of the registry sync
+type RegistrySyncRun struct {
+	ID         uuid.UUID  `json:"run_id" gorm:"type:uuid;default:gen_random_uuid();primary_key"`
+	Trigger    string     `json:"trigger" gorm:"column:trigger;type:text;not null"`
+	StartedAt  time.Time  `json:"started_at" gorm:"column:started_at;type:timestamptz;index"`
+	FinishedAt *time.Time `json:"finished_at,omitempty" gorm:"column:finished_at;type:timestamptz"`
+	Status     string     `json:"status,omitempty" gorm:"column:status;type:text"`
+	// MalformedRecords counts registry records with a missing subscriber_id or unparseable validity.
+	MalformedRecords int                     `json:"malformed_records" gorm:"column:malformed_records"`
+	Domains          []RegistrySyncRunDomain `json:"domains,omitempty" gorm:"foreignKey:RunID"`
+}
+
+func (RegistrySyncRun) TableName() string {
+	return "registry_sync_runs"
+}
+
+// RegistrySyncRunDomain holds the outcome of one domain within a registry sync run
+type RegistrySyncRunDomain struct {
+	RunID                  uuid.UUID `json:"-" gorm:"type:uuid;primaryKey"`
+	Domain                 string    `json:"domain" gorm:"primaryKey;type:text"`
+	Status                 string    `json:"status" gorm:"type:text"`
+	NewSellers             int       `json:"new_sellers"`
+	UpdatedSellers         int       `json:"updated_sellers"`
+	ReactivatedSellers     int       `json:"reactivated_sellers"`
+	UnchangedSellers       int       `json:"unchanged_sellers"`
+	InvalidURLSellers      int       `json:"invalid_url_sellers"`
+	DeactivatedSellers     int       `json:"deactivated_sellers"`
+	TotalSellersInRegistry int       `json:"total_sellers_in_registry"`
+	MalformedRecords       int       `json:"malformed_records"`
+	Error                  *string   `json:"error,omitempty" gorm:"type:text"`
+}
+
+func (RegistrySyncRunDomain) TableName() string {
+	return "registry_sync_run_domains"
+}
+
+// SellerChange is one field change applied to a seller by a registry sync
+type SellerChange struct {
+	ID        int64      `json:"id" gorm:"primaryKey;autoIncrement"`
+	SellerID  string     `json:"seller_id" gorm:"column:seller_id;type:text;not null;index:idx_seller_changes_seller"`
+	Domain    string     `json:"domain" gorm:"column:domain;type:text;not null;index:idx_seller_changes_seller"`
+	RunID     *uuid.UUID `json:"run_id,omitempty" gorm:"column:run_id;type:uuid;index"`
+	Field     string     `json:"field" gorm:"column:field;type:text;not null"`
+	OldValue  *string    `json:"old_value,omitempty" gorm:"column:old_value;type:text"`
+	NewValue  *string    `json:"new_value,omitempty" gorm:"column:new_value;type:text"`
+	ChangedAt time.Time  `json:"changed_at" gorm:"column:changed_at;type:timestamptz;not null"`
+}
+
+func (SellerChange) TableName() string {
+	return "seller_changes"
+}
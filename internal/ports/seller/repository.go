@@ -1,5 +1,24 @@
 package seller
 
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DomainReconciliation is the set of writes that brings one domain in line with the registry
+type DomainReconciliation struct {
+	Domain string
+	// Upserts holds new, reactivated and changed sellers; UnchangedSellerIDs only get last_seen_in_reg bumped.
+	Upserts            []Seller
+	NewSellerIDs       []string
+	UnchangedSellerIDs []string
+	RemovedSellerIDs   []string
+	Changes            []SellerChange
+	SeenAt             time.Time
+}
+
 type SellerRepository interface {
 	InsertSellers(sellers []Seller) error
 	UpdateSellers(sellers []Seller) error
@@ -7,6 +26,14 @@ type SellerRepository interface {
 	GetSellersByFilters(filters map[string]interface{}) ([]Seller, error)
 	GetPendingSellers(domain, status string, limit, offset int) ([]SellerInfo, error)
 	DeactivateSellers(sellerIDs []string, domain string) error
+	ReconcileDomainSellers(ctx context.Context, rec DomainReconciliation) error
+	GetSellersWithInvalidURL(domain string, limit, offset int) ([]Seller, error)
+	ListSellerChanges(sellerID, domain string, limit, offset int) ([]SellerChange, error)
 	UpsertCatalogState(state *SellerCatalogState) error
 	GetSellerCatalogState(sellerID, domain string) (*SellerCatalogState, error)
+	CreateRegistrySyncRun(run *RegistrySyncRun) error
+	FinishRegistrySyncRun(run *RegistrySyncRun) error
+	ListRegistrySyncRuns(limit, offset int) ([]RegistrySyncRun, error)
+	GetRegistrySyncRunByID(runID uuid.UUID) (*RegistrySyncRun, error)
+	ListRegistrySyncRunChanges(runID uuid.UUID, limit, offset int) ([]SellerChange, error)
 }
@@ -14,6 +14,8 @@ import (
 	"gorm.io/gorm"
 
 	"gorm.io/gorm/clause"
+
+	"github.com/google/uuid"
 )
 
 type Service interface {
@@ -90,6 +92,98 @@ func (r *SellerGormRepository) DeactivateSellers(sellerIDs []string, domain stri
 	return r.db.Model(&Seller{}).Where("seller_id IN ? AND domain = ?", sellerIDs, domain).Update("active", false).Error
 }
 
+// ReconcileDomainSellers applies a registry snapshot for one domain in a single transaction:
+// new, reactivated and changed sellers are upserted, unchanged sellers only have
+// last_seen_in_reg bumped, new sellers get a NOT_SYNCED catalog state, sellers missing from the
+// registry are deactivated and every change is appended to seller_changes.
+func (r *SellerGormRepository) ReconcileDomainSellers(ctx context.Context, rec DomainReconciliation) error {
+	log.Info(ctx, fmt.Sprintf("Reconciling domain %s: %d writes (%d new), %d unchanged, %d removed",
+		rec.Domain, len(rec.Upserts), len(rec.NewSellerIDs), len(rec.UnchangedSellerIDs), len(rec.RemovedSellerIDs)))
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(rec.Upserts) > 0 {
+			err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "seller_id"}, {Name: "domain"}},
+				DoUpdates: clause.AssignmentColumns([]string{
+					"status", "type", "subscriber_url", "url_error", "br_id", "uk_id", "signing_public_key",
+					"encr_public_key", "country", "city", "valid_from", "valid_until",
+					"active", "registry_raw", "last_seen_in_reg", "updated_at",
+				}),
+			}).CreateInBatches(&rec.Upserts, 500).Error
+			if err != nil {
+				return fmt.Errorf("upsert sellers: %w", err)
+			}
+		}
+
+		if len(rec.UnchangedSellerIDs) > 0 {
+			err := tx.Model(&Seller{}).
+				Where("seller_id IN ? AND domain = ?", rec.UnchangedSellerIDs, rec.Domain).
+				UpdateColumn("last_seen_in_reg", rec.SeenAt).Error
+			if err != nil {
+				return fmt.Errorf("touch unchanged sellers: %w", err)
+			}
+		}
+
+		if len(rec.NewSellerIDs) > 0 {
+			states := make([]SellerCatalogState, 0, len(rec.NewSellerIDs))
+			for _, id := range rec.NewSellerIDs {
+				states = append(states, SellerCatalogState{SellerID: id, Domain: rec.Domain, Status: CatalogStatusNotSynced})
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&states, 500).Error; err != nil {
+				return fmt.Errorf("create catalog states: %w", err)
+			}
+		}
+
+		if len(rec.RemovedSellerIDs) > 0 {
+			if err := tx.Model(&Seller{}).Where("seller_id IN ? AND domain = ?", rec.RemovedSellerIDs, rec.Domain).Update("active", false).Error; err != nil {
+				return fmt.Errorf("deactivate sellers: %w", err)
+			}
+		}
+
+		if len(rec.Changes) > 0 {
+			if err := tx.CreateInBatches(&rec.Changes, 500).Error; err != nil {
+				return fmt.Errorf("record seller changes: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetSellersWithInvalidURL returns active sellers with an unusable subscriber_url, optionally for
+// one domain, fetching one extra record for the hasMore check
+func (r *SellerGormRepository) GetSellersWithInvalidURL(domain string, limit, offset int) ([]Seller, error) {
+	var sellers []Seller
+	query := r.db.Where("active = ? AND url_error IS NOT NULL", true)
+	if domain != "" {
+		query = query.Where("domain = ?", domain)
+	}
+	err := query.Order("domain, seller_id").Limit(limit + 1).Offset(offset).Find(&sellers).Error
+	return sellers, err
+}
+
+// ListSellerChanges returns a seller's change history newest first, optionally for one domain,
+// fetching one extra record for the hasMore check
+func (r *SellerGormRepository) ListSellerChanges(sellerID, domain string, limit, offset int) ([]SellerChange, error) {
+	var changes []SellerChange
+	query := r.db.Where("seller_id = ?", sellerID)
+	if domain != "" {
+		query = query.Where("domain = ?", domain)
+	}
+	err := query.Order("changed_at DESC, id DESC").Limit(limit + 1).Offset(offset).Find(&changes).Error
+	return changes, err
+}
+
+// ListRegistrySyncRunChanges returns the seller changes a run applied ordered by domain and
+// seller_id, fetching one extra record for the hasMore check
+func (r *SellerGormRepository) ListRegistrySyncRunChanges(runID uuid.UUID, limit, offset int) ([]SellerChange, error) {
+	var changes []SellerChange
+	err := r.db.Where("run_id = ?", runID).
+		Order("domain, seller_id, id").
+		Limit(limit + 1).
+		Offset(offset).
+		Find(&changes).Error
+	return changes, err
+}
+
 func (r *SellerGormRepository) UpsertCatalogState(state *SellerCatalogState) error {
 	return r.db.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "seller_id"}, {Name: "domain"}},
@@ -150,3 +244,45 @@ func (r *SellerGormRepository) GetSellerCatalogState(sellerID, domain string) (*
 	}
 	return &state, nil
 }
+
+func (r *SellerGormRepository) CreateRegistrySyncRun(run *RegistrySyncRun) error {
+	return r.db.Omit("Domains").Create(run).Error
+}
+
+// FinishRegistrySyncRun stores the finish time and the per-domain results of a run
+func (r *SellerGormRepository) FinishRegistrySyncRun(run *RegistrySyncRun) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&RegistrySyncRun{}).Where("id = ?", run.ID).
+			Updates(map[string]interface{}{"finished_at": run.FinishedAt, "status": run.Status, "malformed_records": run.MalformedRecords}).Error; err != nil {
+			return err
+		}
+		if len(run.Domains) == 0 {
+			return nil
+		}
+		for i := range run.Domains {
+			run.Domains[i].RunID = run.ID
+		}
+		return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&run.Domains).Error
+	})
+}
+
+// ListRegistrySyncRuns returns runs newest first, fetching one extra record for the hasMore check
+func (r *SellerGormRepository) ListRegistrySyncRuns(limit, offset int) ([]RegistrySyncRun, error) {
+	var runs []RegistrySyncRun
+	err := r.db.Preload("Domains", func(db *gorm.DB) *gorm.DB { return db.Order("domain") }).
+		Order("started_at DESC").
+		Limit(limit + 1).
+		Offset(offset).
+		Find(&runs).Error
+	return runs, err
+}
+
+func (r *SellerGormRepository) GetRegistrySyncRunByID(runID uuid.UUID) (*RegistrySyncRun, error) {
+	var run RegistrySyncRun
+	err := r.db.Preload("Domains", func(db *gorm.DB) *gorm.DB { return db.Order("domain") }).
+		First(&run, "id = ?", runID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
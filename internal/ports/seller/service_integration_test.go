@@ -0,0 +1,123 @@
+//go:build integration
+
+package seller_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	sellerPorts "adapter/internal/ports/seller"
+	"adapter/internal/testharness"
+)
+
+func newSellerRepository(t *testing.T) *sellerPorts.SellerGormRepository {
+	t.Helper()
+	db := testharness.NewPostgres(t, &sellerPorts.Seller{}, &sellerPorts.SellerCatalogState{}, &sellerPorts.SellerChange{})
+	return sellerPorts.NewSellerRepository(db)
+}
+
+func storedSeller(t *testing.T, repo *sellerPorts.SellerGormRepository, sellerID string) sellerPorts.Seller {
+	t.Helper()
+	sellers, err := repo.GetSellersByFilters(map[string]interface{}{"seller_id": sellerID, "domain": testharness.TestDomain})
+	if err != nil || len(sellers) != 1 {
+		t.Fatalf("GetSellersByFilters(%s) = %d sellers, %v", sellerID, len(sellers), err)
+	}
+	return sellers[0]
+}
+
+func TestReconcileDomainSellersUpsertsRegistryColumns(t *testing.T) {
+	repo := newSellerRepository(t)
+	seenAt := time.Now().UTC().Truncate(time.Second)
+	from := seenAt.Add(-24 * time.Hour)
+	until := seenAt.Add(24 * time.Hour)
+	urlError := "subscriber_url has no host"
+
+	original := sellerPorts.Seller{
+		SellerID: "seller-1.example", Domain: testharness.TestDomain, Status: "SUBSCRIBED", Type: "BPP",
+		SubscriberURL: "https://seller-1.example/ondc", BrID: "br-1", UkID: "key-1",
+		SigningKey: "signing-1", EncryptionKey: "encr-1", Country: "IND", City: "std:080",
+		ValidFrom: &from, ValidUntil: &until, Active: true, RegistryRaw: `{"subscriber_id":"seller-1.example"}`,
+		LastSeenInReg: seenAt,
+	}
+	err := repo.ReconcileDomainSellers(context.Background(), sellerPorts.DomainReconciliation{
+		Domain:       testharness.TestDomain,
+		Upserts:      []sellerPorts.Seller{original, {SellerID: "seller-2.example", Domain: testharness.TestDomain, Active: true, RegistryRaw: "{}", LastSeenInReg: seenAt}},
+		NewSellerIDs: []string{"seller-1.example", "seller-2.example"},
+		SeenAt:       seenAt,
+	})
+	if err != nil {
+		t.Fatalf("ReconcileDomainSellers: %v", err)
+	}
+	if state, err := repo.GetSellerCatalogState("seller-1.example", testharness.TestDomain); err != nil || state.Status != sellerPorts.CatalogStatusNotSynced {
+		t.Errorf("catalog state = %+v, %v, want NOT_SYNCED", state, err)
+	}
+
+	// Every registry column is overwritten, including ones cleared to NULL.
+	later := seenAt.Add(time.Hour)
+	updated := sellerPorts.Seller{
+		SellerID: "seller-1.example", Domain: testharness.TestDomain, Status: "INITIATED", Type: "BPP",
+		SubscriberURL: "seller-1.example", URLError: &urlError, BrID: "br-2", UkID: "key-2",
+		SigningKey: "signing-2", EncryptionKey: "encr-2", Country: "IND", City: "std:011",
+		ValidFrom: nil, ValidUntil: nil, Active: true, RegistryRaw: `{"subscriber_id":"seller-1.example","status":"INITIATED"}`,
+		LastSeenInReg: later,
+	}
+	oldURL, newURL := original.SubscriberURL, updated.SubscriberURL
+	err = repo.ReconcileDomainSellers(context.Background(), sellerPorts.DomainReconciliation{
+		Domain:           testharness.TestDomain,
+		Upserts:          []sellerPorts.Seller{updated},
+		RemovedSellerIDs: []string{"seller-2.example"},
+		Changes: []sellerPorts.SellerChange{{
+			SellerID: "seller-1.example", Domain: testharness.TestDomain, Field: "subscriber_url",
+			OldValue: &oldURL, NewValue: &newURL, ChangedAt: later,
+		}},
+		SeenAt: later,
+	})
+	if err != nil {
+		t.Fatalf("ReconcileDomainSellers: %v", err)
+	}
+
+	got := storedSeller(t, repo, "seller-1.example")
+	type registryColumns struct {
+		Status, Type, SubscriberURL, BrID, UkID, SigningKey, EncryptionKey, Country, City string
+		URLError                                                                          *string
+		ValidFrom, ValidUntil                                                             *time.Time
+		Active                                                                            bool
+	}
+	columns := func(s sellerPorts.Seller) registryColumns {
+		return registryColumns{s.Status, s.Type, s.SubscriberURL, s.BrID, s.UkID, s.SigningKey, s.EncryptionKey, s.Country, s.City, s.URLError, s.ValidFrom, s.ValidUntil, s.Active}
+	}
+	if !reflect.DeepEqual(columns(got), columns(updated)) {
+		t.Errorf("stored registry columns = %+v, want %+v", columns(got), columns(updated))
+	}
+	if !got.LastSeenInReg.Equal(later) {
+		t.Errorf("last_seen_in_reg = %v, want %v", got.LastSeenInReg, later)
+	}
+	// jsonb normalises formatting, so registry_raw is compared decoded.
+	var gotRaw, wantRaw map[string]interface{}
+	if err := json.Unmarshal([]byte(got.RegistryRaw), &gotRaw); err != nil || json.Unmarshal([]byte(updated.RegistryRaw), &wantRaw) != nil || !reflect.DeepEqual(gotRaw, wantRaw) {
+		t.Errorf("registry_raw = %s, want %s", got.RegistryRaw, updated.RegistryRaw)
+	}
+	if removed := storedSeller(t, repo, "seller-2.example"); removed.Active {
+		t.Error("seller-2 should be deactivated")
+	}
+	changes, err := repo.ListSellerChanges("seller-1.example", testharness.TestDomain, 10, 0)
+	if err != nil || len(changes) != 1 || *changes[0].NewValue != newURL {
+		t.Errorf("seller changes = %+v, %v", changes, err)
+	}
+
+	// Unchanged sellers only have last_seen_in_reg bumped.
+	latest := later.Add(time.Hour)
+	if err := repo.ReconcileDomainSellers(context.Background(), sellerPorts.DomainReconciliation{
+		Domain:             testharness.TestDomain,
+		UnchangedSellerIDs: []string{"seller-1.example"},
+		SeenAt:             latest,
+	}); err != nil {
+		t.Fatalf("ReconcileDomainSellers: %v", err)
+	}
+	if touched := storedSeller(t, repo, "seller-1.example"); !touched.LastSeenInReg.Equal(latest) || touched.Status != "INITIATED" {
+		t.Errorf("unchanged seller = %+v, want only last_seen_in_reg bumped to %v", touched, latest)
+	}
+}
@@ -16,6 +16,25 @@ const (
 	ErrGetPendingSellers         = "Failed to get pending catalog sync sellers"
 	ErrGetSyncStatus             = "Failed to get sync status"
 	ErrRecordNotFound            = "Record not found for the specified seller_id and domain"
+	ErrGetSellerHistory          = "Failed to get seller history"
+	ErrGetInvalidURLSellers      = "Failed to get sellers with invalid subscriber_url"
 	// Registry Sync Errors
 	ErrFailedToStartRegistrySync = "Failed to start registry sync"
+	ErrRegistrySyncIncomplete    = "Registry sync failed for one or more domains"
+	ErrGetRegistrySyncRuns       = "Failed to get registry sync runs"
+	ErrFailedToListLocks         = "Failed to list locks"
+
+	// Authentication Errors
+	ErrUnauthorized      = "Unauthorized"
+	ErrMissingSignature  = "Authorization header with ONDC signature is required"
+	ErrInvalidSignature  = "Invalid Signature"
+	ErrSignatureExpired  = "Signature is outside its created/expires window"
+	ErrUnknownSigningKey = "Unable to resolve signing public key for subscriber"
+
+	// Broadcast Errors
+	ErrOnSearchContextRequired = "context with transaction_id and bpp_id is required"
+	ErrOnSearchSignerMismatch  = "on_search signer does not match context.bpp_id"
+	ErrBroadcastJobFinished    = "Broadcast job has already finished"
+	ErrBapIDRequired           = "bap_id query parameter is required"
+	ErrInvalidStreamToken      = "Invalid or expired event stream token"
 )
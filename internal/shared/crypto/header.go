@@ -0,0 +1,73 @@
+package crypto
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SignatureHeader holds the parsed fields of an ONDC Authorization header
+type SignatureHeader struct {
+	SubscriberID string
+	UniqueKeyID  string
+	Algorithm    string
+	Created      int
+	Expires      int
+	Headers      string
+	Signature    string
+}
+
+// ParseSignatureHeader parses an ONDC `Signature keyId="sub|ukid|ed25519",...` header value
+func ParseSignatureHeader(header string) (*SignatureHeader, error) {
+	header = strings.TrimSpace(header)
+	if !strings.HasPrefix(header, "Signature ") {
+		return nil, fmt.Errorf("authorization header is not a Signature header")
+	}
+
+	params := make(map[string]string)
+	for _, part := range strings.Split(strings.TrimPrefix(header, "Signature "), ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			continue
+		}
+		params[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+
+	keyParts := strings.Split(params["keyId"], "|")
+	if len(keyParts) != 3 || keyParts[0] == "" || keyParts[1] == "" {
+		return nil, fmt.Errorf("invalid keyId %q in signature header", params["keyId"])
+	}
+	if keyParts[2] != "ed25519" {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", keyParts[2])
+	}
+
+	created, err := strconv.Atoi(params["created"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid created value in signature header: %w", err)
+	}
+	expires, err := strconv.Atoi(params["expires"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid expires value in signature header: %w", err)
+	}
+	if params["signature"] == "" {
+		return nil, fmt.Errorf("signature missing from signature header")
+	}
+
+	return &SignatureHeader{
+		SubscriberID: keyParts[0],
+		UniqueKeyID:  keyParts[1],
+		Algorithm:    keyParts[2],
+		Created:      created,
+		Expires:      expires,
+		Headers:      params["headers"],
+		Signature:    params["signature"],
+	}, nil
+}
+
+// String formats the header in the form expected by ONDC participants
+func (h *SignatureHeader) String() string {
+	return fmt.Sprintf(
+		`Signature keyId="%s|%s|ed25519",algorithm="ed25519",created="%d",expires="%d",headers="(created) (expires) digest",signature="%s"`,
+		h.SubscriberID, h.UniqueKeyID, h.Created, h.Expires, h.Signature,
+	)
+}
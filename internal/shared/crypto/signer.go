@@ -0,0 +1,69 @@
+package crypto
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	HeaderAuthorization        = "Authorization"
+	HeaderGatewayAuthorization = "X-Gateway-Authorization"
+)
+
+// SignerConfig holds the subscriber credentials used to sign outbound requests
+type SignerConfig struct {
+	SubscriberID string
+	UniqueKeyID  string
+	PrivateKey   string
+	TTL          time.Duration
+	Gateway      bool
+}
+
+// RequestSigner builds ONDC signature headers for outbound requests
+type RequestSigner struct {
+	crypto *ONDCCrypto
+	cfg    SignerConfig
+}
+
+func NewRequestSigner(cfg SignerConfig) *RequestSigner {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 30 * time.Second
+	}
+	return &RequestSigner{crypto: NewONDCCrypto(), cfg: cfg}
+}
+
+// Sign returns the Authorization header value for the exact body bytes that will be sent
+func (s *RequestSigner) Sign(body []byte) (string, error) {
+	if s.cfg.PrivateKey == "" {
+		return "", fmt.Errorf("PRIVATE_KEY not configured")
+	}
+	created := int(time.Now().Unix())
+	ttl := int(s.cfg.TTL.Seconds())
+	signature, err := s.crypto.SignRequest(s.cfg.PrivateKey, body, created, ttl)
+	if err != nil {
+		return "", err
+	}
+	header := &SignatureHeader{
+		SubscriberID: s.cfg.SubscriberID,
+		UniqueKeyID:  s.cfg.UniqueKeyID,
+		Algorithm:    "ed25519",
+		Created:      created,
+		Expires:      created + ttl,
+		Signature:    signature,
+	}
+	return header.String(), nil
+}
+
+// Headers returns the signature headers for the body. When signing as a gateway the
+// signature is also sent as X-Gateway-Authorization.
+func (s *RequestSigner) Headers(body []byte) (map[string]string, error) {
+	authHeader, err := s.Sign(body)
+	if err != nil {
+		return nil, err
+	}
+	headers := map[string]string{HeaderAuthorization: authHeader}
+	if s.cfg.Gateway {
+		headers[HeaderGatewayAuthorization] = authHeader
+	}
+	return headers, nil
+}
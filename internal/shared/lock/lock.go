@@ -0,0 +1,177 @@
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+)
+
+const (
+	keyPrefix = "lock:"
+	// Markers live under their own prefix so that Holders only lists locks.
+	markerPrefix = "marker:"
+)
+
+// ErrNotAcquired is returned when the lock is held by someone else.
+var ErrNotAcquired = errors.New("lock is held by another owner")
+
+// ErrLockLost is returned when renewing or releasing a lock that has expired or changed owner.
+var ErrLockLost = errors.New("lock is no longer held")
+
+// The value is compared before renewing or deleting so an owner can never touch a lock
+// that expired and was taken over by someone else.
+var (
+	renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// Holder describes who holds a lock and until when.
+type Holder struct {
+	Name       string    `json:"name"`
+	Owner      string    `json:"owner"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+type lockValue struct {
+	Owner      string    `json:"owner"`
+	Token      string    `json:"token"`
+	AcquiredAt time.Time `json:"acquired_at"`
+}
+
+// Locker hands out named locks backed by Redis SET NX PX.
+type Locker struct {
+	client *redis.Client
+	owner  string
+}
+
+// NewLocker creates a Locker that acquires locks on behalf of owner.
+func NewLocker(client *redis.Client, owner string) *Locker {
+	return &Locker{client: client, owner: owner}
+}
+
+// Lock is a held lock. It expires after its TTL unless renewed.
+type Lock struct {
+	client *redis.Client
+	name   string
+	key    string
+	value  string
+	ttl    time.Duration
+}
+
+// Acquire takes the named lock for ttl, returning ErrNotAcquired if it is already held.
+func (l *Locker) Acquire(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	return l.acquire(ctx, keyPrefix, name, ttl)
+}
+
+// Mark sets the named marker for ttl, returning ErrNotAcquired if it is already set. A marker
+// records that something happened rather than that someone is working, so Holders leaves it out.
+func (l *Locker) Mark(ctx context.Context, name string, ttl time.Duration) (*Lock, error) {
+	return l.acquire(ctx, markerPrefix, name, ttl)
+}
+
+func (l *Locker) acquire(ctx context.Context, prefix, name string, ttl time.Duration) (*Lock, error) {
+	value, err := json.Marshal(lockValue{Owner: l.owner, Token: uuid.NewString(), AcquiredAt: time.Now().UTC()})
+	if err != nil {
+		return nil, err
+	}
+	ok, err := l.client.SetNX(ctx, prefix+name, value, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", name, err)
+	}
+	if !ok {
+		return nil, ErrNotAcquired
+	}
+	return &Lock{client: l.client, name: name, key: prefix + name, value: string(value), ttl: ttl}, nil
+}
+
+// Holders lists the locks currently held. Markers set with Mark are not included.
+func (l *Locker) Holders(ctx context.Context) ([]Holder, error) {
+	holders := []Holder{}
+	iter := l.client.Scan(ctx, 0, keyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		raw, err := l.client.Get(ctx, key).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ttl, err := l.client.PTTL(ctx, key).Result()
+		if err != nil {
+			return nil, err
+		}
+		var value lockValue
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			continue
+		}
+		holders = append(holders, Holder{
+			Name:       strings.TrimPrefix(key, keyPrefix),
+			Owner:      value.Owner,
+			AcquiredAt: value.AcquiredAt,
+			ExpiresAt:  time.Now().Add(ttl).UTC(),
+		})
+	}
+	return holders, iter.Err()
+}
+
+// Renew extends the lock by its TTL, returning ErrLockLost if it is no longer ours.
+func (lk *Lock) Renew(ctx context.Context) error {
+	renewed, err := renewScript.Run(ctx, lk.client, []string{lk.key}, lk.value, lk.ttl.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to renew lock %s: %w", lk.name, err)
+	}
+	if renewed == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// KeepAlive renews the lock every third of its TTL until ctx is done. It returns a channel
+// that is closed if the lock is lost.
+func (lk *Lock) KeepAlive(ctx context.Context) <-chan struct{} {
+	lost := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(lk.ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := lk.Renew(ctx); errors.Is(err, ErrLockLost) {
+					close(lost)
+					return
+				}
+			}
+		}
+	}()
+	return lost
+}
+
+// Release gives the lock up if it is still ours.
+func (lk *Lock) Release(ctx context.Context) error {
+	released, err := releaseScript.Run(ctx, lk.client, []string{lk.key}, lk.value).Int()
+	if err != nil {
+		return fmt.Errorf("failed to release lock %s: %w", lk.name, err)
+	}
+	if released == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
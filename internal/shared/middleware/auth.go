@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"adapter/internal/shared/constants"
+	"adapter/internal/shared/crypto"
+	"adapter/internal/shared/log"
+	"adapter/internal/shared/utils"
+)
+
+const (
+	AuthSchemeSignature = "signature"
+	AuthSchemeAPIKey    = "api_key"
+	AuthSchemeNone      = "none"
+)
+
+// KeyResolver looks up the signing public key registered for an ONDC subscriber
+type KeyResolver interface {
+	ResolveSigningKey(ctx context.Context, subscriberID, ukID string) (string, error)
+}
+
+// AuthConfig configures the authentication scheme applied to a route group
+type AuthConfig struct {
+	Scheme    string
+	Header    string
+	APIKey    string
+	Realm     string
+	ClockSkew time.Duration
+	Resolver  KeyResolver
+}
+
+// RouteAuth holds the authentication middleware for each route group
+type RouteAuth struct {
+	Public   fiber.Handler
+	Internal fiber.Handler
+}
+
+// NewAuthMiddleware builds the middleware for the configured scheme
+func NewAuthMiddleware(cfg AuthConfig) (fiber.Handler, error) {
+	switch cfg.Scheme {
+	case AuthSchemeSignature:
+		if cfg.Resolver == nil {
+			return nil, fmt.Errorf("signature auth requires a key resolver")
+		}
+		return SignatureAuthMiddleware(cfg), nil
+	case AuthSchemeAPIKey:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("api_key auth requires an API key")
+		}
+		return APIKeyAuthMiddleware(cfg), nil
+	case AuthSchemeNone, "":
+		return func(c *fiber.Ctx) error { return c.Next() }, nil
+	default:
+		return nil, fmt.Errorf("unknown auth scheme %q", cfg.Scheme)
+	}
+}
+
+// SignatureAuthMiddleware verifies the ONDC signature of the request body against the
+// signing public key registered for the subscriber in the keyId.
+func SignatureAuthMiddleware(cfg AuthConfig) fiber.Handler {
+	header := cfg.Header
+	if header == "" {
+		header = fiber.HeaderAuthorization
+	}
+	verifier := crypto.NewONDCCrypto()
+
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+
+		authHeader := c.Get(header)
+		if authHeader == "" {
+			return signatureNack(c, cfg.Realm, constants.ErrMissingSignature)
+		}
+
+		sig, err := crypto.ParseSignatureHeader(authHeader)
+		if err != nil {
+			log.Warnf(ctx, "Rejecting request with malformed %s header: %v", header, err)
+			return signatureNack(c, cfg.Realm, constants.ErrInvalidSignature)
+		}
+
+		now := time.Now()
+		if time.Unix(int64(sig.Created), 0).After(now.Add(cfg.ClockSkew)) ||
+			time.Unix(int64(sig.Expires), 0).Before(now.Add(-cfg.ClockSkew)) ||
+			sig.Expires <= sig.Created {
+			log.Warnf(ctx, "Rejecting request from %s: signature outside validity window (created=%d, expires=%d)", sig.SubscriberID, sig.Created, sig.Expires)
+			return signatureNack(c, cfg.Realm, constants.ErrSignatureExpired)
+		}
+
+		publicKey, err := cfg.Resolver.ResolveSigningKey(ctx, sig.SubscriberID, sig.UniqueKeyID)
+		if err != nil {
+			log.Errorf(ctx, err, "Failed to resolve signing key for subscriber %s (ukId %s)", sig.SubscriberID, sig.UniqueKeyID)
+			return signatureNack(c, cfg.Realm, constants.ErrUnknownSigningKey)
+		}
+
+		valid, err := verifier.VerifyRequest(publicKey, c.Body(), sig.Created, sig.Expires, sig.Signature)
+		if err != nil || !valid {
+			log.Warnf(ctx, "Rejecting request from %s: signature verification failed", sig.SubscriberID)
+			return signatureNack(c, cfg.Realm, constants.ErrInvalidSignature)
+		}
+
+		c.Locals("subscriber_id", sig.SubscriberID)
+		return c.Next()
+	}
+}
+
+// APIKeyAuthMiddleware checks the configured API key header against a static key
+func APIKeyAuthMiddleware(cfg AuthConfig) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(cfg.Header)
+		if key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(cfg.APIKey)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(utils.ApiResponse{
+				Success: false,
+				Message: constants.ErrUnauthorized,
+			})
+		}
+		return c.Next()
+	}
+}
+
+func signatureNack(c *fiber.Ctx, realm, message string) error {
+	c.Set(fiber.HeaderWWWAuthenticate, fmt.Sprintf(`Signature realm="%s",headers="(created) (expires) digest"`, realm))
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"message": fiber.Map{"ack": fiber.Map{"status": "NACK"}},
+		"error": fiber.Map{
+			"type":    "POLICY-ERROR",
+			"code":    "10001",
+			"message": message,
+		},
+	})
+}
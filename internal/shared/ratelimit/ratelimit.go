@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// minSweepInterval bounds how often idle buckets are pruned so high rates don't
+// turn every reservation into a full map scan.
+const minSweepInterval = time.Minute
+
+// HostLimiter applies an independent token bucket rate limit to every host.
+// Buckets idle long enough to refill completely are evicted, since a fresh bucket
+// behaves identically.
+type HostLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*bucket
+	idleAfter time.Duration
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewHostLimiter creates a limiter allowing ratePerSecond requests per host with the given burst.
+// A non-positive rate disables limiting.
+func NewHostLimiter(ratePerSecond float64, burst int) *HostLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	l := &HostLimiter{
+		rate:    ratePerSecond,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+	if ratePerSecond > 0 {
+		l.idleAfter = time.Duration(float64(burst) / ratePerSecond * float64(time.Second))
+	}
+	l.lastSweep = l.now()
+	return l
+}
+
+// Wait blocks until a request to host is allowed or the context is done.
+func (l *HostLimiter) Wait(ctx context.Context, host string) error {
+	if l.rate <= 0 {
+		return nil
+	}
+
+	for {
+		delay := l.reserve(host)
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve takes a token for host if one is available, otherwise it returns how long
+// the caller should wait before trying again.
+func (l *HostLimiter) reserve(host string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[host]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[host] = b
+	}
+
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep drops buckets that have been idle long enough to be full again. The caller
+// must hold l.mu.
+func (l *HostLimiter) sweep(now time.Time) {
+	interval := l.idleAfter
+	if interval < minSweepInterval {
+		interval = minSweepInterval
+	}
+	if now.Sub(l.lastSweep) < interval {
+		return
+	}
+	l.lastSweep = now
+
+	for host, b := range l.buckets {
+		if now.Sub(b.last) >= l.idleAfter {
+			delete(l.buckets, host)
+		}
+	}
+}
+
+// Semaphore caps the number of concurrent holders.
+type Semaphore struct {
+	slots chan struct{}
+}
+
+// NewSemaphore creates a semaphore with size slots. A non-positive size disables the cap.
+func NewSemaphore(size int) *Semaphore {
+	if size <= 0 {
+		return &Semaphore{}
+	}
+	return &Semaphore{slots: make(chan struct{}, size)}
+}
+
+// Acquire blocks until a slot is free or the context is done.
+func (s *Semaphore) Acquire(ctx context.Context) error {
+	if s.slots == nil {
+		return nil
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire.
+func (s *Semaphore) Release() {
+	if s.slots == nil {
+		return
+	}
+	<-s.slots
+}
@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHostLimiterEvictsIdleBuckets(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewHostLimiter(0.1, 5) // refills in 50s
+	l.now = func() time.Time { return now }
+	l.lastSweep = now
+
+	l.reserve("idle.example")
+	now = now.Add(30 * time.Second)
+	l.reserve("busy.example")
+
+	now = now.Add(minSweepInterval - 30*time.Second)
+	l.reserve("busy.example")
+
+	if _, ok := l.buckets["idle.example"]; ok {
+		t.Fatal("idle bucket was not evicted")
+	}
+	if _, ok := l.buckets["busy.example"]; !ok {
+		t.Fatal("recently used bucket was evicted")
+	}
+}
+
+func TestHostLimiterKeepsBucketsThatHaveNotRefilled(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewHostLimiter(0.01, 5) // refills in 500s
+	l.now = func() time.Time { return now }
+	l.lastSweep = now
+
+	for i := 0; i < 5; i++ {
+		l.reserve("slow.example")
+	}
+	now = now.Add(2 * minSweepInterval)
+	l.reserve("other.example")
+
+	if _, ok := l.buckets["slow.example"]; !ok {
+		t.Fatal("bucket evicted before it refilled, which would reset its rate limit")
+	}
+}
@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// PubSub publishes and subscribes to messages on Redis channels.
+type PubSub struct {
+	client *redis.Client
+}
+
+// NewPubSub creates a new PubSub on the given client.
+func NewPubSub(client *redis.Client) *PubSub {
+	return &PubSub{client: client}
+}
+
+// Publish sends a message to every subscriber of the channel.
+func (p *PubSub) Publish(ctx context.Context, channel string, payload []byte) error {
+	return p.client.Publish(ctx, channel, payload).Err()
+}
+
+// Subscribe returns the messages published on the channel until ctx is done or the
+// returned close function is called. The subscription is active when Subscribe returns.
+func (p *PubSub) Subscribe(ctx context.Context, channel string) (<-chan []byte, func() error, error) {
+	sub := p.client.Subscribe(ctx, channel)
+	if _, err := sub.Receive(ctx); err != nil {
+		sub.Close()
+		return nil, nil, err
+	}
+
+	messages := make(chan []byte)
+	go func() {
+		defer close(messages)
+		for msg := range sub.Channel() {
+			select {
+			case messages <- []byte(msg.Payload):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return messages, sub.Close, nil
+}
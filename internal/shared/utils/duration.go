@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+(?:\.\d+)?)W)?(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// ParseISODuration parses an ISO 8601 duration such as "PT30S" or "P1DT12H", as used in ONDC
+// ttl fields. Years and months are not supported since their length is ambiguous.
+func ParseISODuration(s string) (time.Duration, error) {
+	match := isoDurationPattern.FindStringSubmatch(s)
+	if match == nil || s == "P" || s[len(s)-1] == 'T' {
+		return 0, fmt.Errorf("invalid ISO 8601 duration %q", s)
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var total time.Duration
+	for i, unit := range units {
+		if match[i+1] == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(match[i+1], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO 8601 duration %q: %w", s, err)
+		}
+		total += time.Duration(value * float64(unit))
+	}
+	return total, nil
+}
@@ -0,0 +1,20 @@
+package utils
+
+const (
+	// DefaultPageLimit is the page size used when a request does not set limit.
+	DefaultPageLimit = 100
+	// MaxPageLimit caps the page size a request may ask for.
+	MaxPageLimit = 1000
+)
+
+// ClampLimit bounds a requested page size to [1, MaxPageLimit], so repositories never see a
+// limit that would make a query unbounded or empty.
+func ClampLimit(limit int) int {
+	if limit < 1 {
+		return 1
+	}
+	if limit > MaxPageLimit {
+		return MaxPageLimit
+	}
+	return limit
+}
@@ -0,0 +1,19 @@
+package utils
+
+import "testing"
+
+func TestClampLimit(t *testing.T) {
+	tests := map[int]int{
+		-5:               1,
+		0:                1,
+		1:                1,
+		250:              250,
+		MaxPageLimit:     MaxPageLimit,
+		MaxPageLimit + 1: MaxPageLimit,
+	}
+	for limit, want := range tests {
+		if got := ClampLimit(limit); got != want {
+			t.Errorf("ClampLimit(%d) = %d, want %d", limit, got, want)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// registryTimeLayouts are the timestamp formats seen in ONDC registry responses. Layouts
+// without a zone are read as UTC.
+var registryTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z0700",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// ParseRegistryTime parses a registry timestamp such as "2024-05-01T10:00:00.000Z",
+// "2024-05-01T10:00:00" or "2024-05-01 10:00:00+05:30". Fractional seconds are optional.
+func ParseRegistryTime(s string) (time.Time, error) {
+	value := strings.TrimSpace(s)
+	if value == "" {
+		return time.Time{}, fmt.Errorf("empty timestamp")
+	}
+	for _, layout := range registryTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised timestamp %q", s)
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRegistryTime(t *testing.T) {
+	ist := time.FixedZone("", 5*3600+1800)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2024-05-01T10:00:00.000Z", want: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{value: "2024-05-01T10:00:00Z", want: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{value: "2024-05-01T10:00:00.123456+05:30", want: time.Date(2024, 5, 1, 10, 0, 0, 123456000, ist)},
+		{value: "2024-05-01T10:00:00+0530", want: time.Date(2024, 5, 1, 10, 0, 0, 0, ist)},
+		{value: "2024-05-01T10:00:00", want: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{value: "2024-05-01 10:00:00+05:30", want: time.Date(2024, 5, 1, 10, 0, 0, 0, ist)},
+		{value: "2024-05-01 10:00:00.5", want: time.Date(2024, 5, 1, 10, 0, 0, 500000000, time.UTC)},
+		{value: " 2024-05-01 ", want: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "", wantErr: true},
+		{value: "   ", wantErr: true},
+		{value: "next tuesday", wantErr: true},
+		{value: "2024-13-01T10:00:00Z", wantErr: true},
+		{value: "01/05/2024", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRegistryTime(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseRegistryTime(%q) = %v, want an error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("ParseRegistryTime(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package testharness
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"adapter/internal/ports/broadcast"
+	"adapter/internal/shared/crypto"
+)
+
+// BPPBehaviour is how the fake BPP answers /search for a seller
+type BPPBehaviour string
+
+const (
+	BPPAck         BPPBehaviour = "ACK"
+	BPPNack        BPPBehaviour = "NACK"
+	BPPServerError BPPBehaviour = "SERVER_ERROR"
+	// BPPHang never answers; the request ends when the caller gives up.
+	BPPHang BPPBehaviour = "HANG"
+)
+
+// OnSearchReply is the on_search callback a seller queues after ACKing /search. An empty
+// Decision sends a plain catalog; ErrorCode sends an error instead of a catalog.
+type OnSearchReply struct {
+	Decision     string
+	Reason       string
+	ErrorCode    string
+	ErrorMessage string
+}
+
+// SearchCall is a /search request received by the fake BPP. SignatureError is why its
+// signature was rejected, or nil when it verified.
+type SearchCall struct {
+	SellerID       string
+	Authorization  string
+	SignatureError error
+	Payload        broadcast.SearchPayload
+}
+
+// BPP is a fake seller app hosting any number of sellers under /<seller_id>. Each seller
+// ACKs by default. Like a real seller it answers 401 to /search requests that are not signed
+// with a trusted key.
+type BPP struct {
+	Server *httptest.Server
+
+	mu          sync.Mutex
+	trustedKeys map[string]string
+	behaviours  map[string]BPPBehaviour
+	replies     map[string]OnSearchReply
+	calls       []SearchCall
+	queued      []broadcast.OnSearchRequest
+	stop        chan struct{}
+}
+
+// NewBPP starts a fake BPP that is shut down when the test ends
+func NewBPP(t testing.TB) *BPP {
+	b := &BPP{
+		trustedKeys: make(map[string]string),
+		behaviours:  make(map[string]BPPBehaviour),
+		replies:     make(map[string]OnSearchReply),
+		stop:        make(chan struct{}),
+	}
+	b.Server = httptest.NewServer(http.HandlerFunc(b.handleSearch))
+	t.Cleanup(func() {
+		close(b.stop)
+		b.Server.Close()
+	})
+	return b
+}
+
+// SubscriberURL is the subscriber_url to register for sellerID
+func (b *BPP) SubscriberURL(sellerID string) string {
+	return b.Server.URL + "/" + sellerID
+}
+
+// Trust accepts /search requests signed with publicKey under subscriberID and ukID
+func (b *BPP) Trust(subscriberID, ukID, publicKey string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trustedKeys[subscriberID+"|"+ukID] = publicKey
+}
+
+// SetBehaviour sets how sellerID answers /search
+func (b *BPP) SetBehaviour(sellerID string, behaviour BPPBehaviour) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.behaviours[sellerID] = behaviour
+}
+
+// ReplyOnSearch makes sellerID queue an on_search callback for the bap_uri after ACKing
+func (b *BPP) ReplyOnSearch(sellerID string, reply OnSearchReply) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.replies[sellerID] = reply
+}
+
+// Calls returns the /search requests received so far
+func (b *BPP) Calls() []SearchCall {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]SearchCall(nil), b.calls...)
+}
+
+// SendCallbacks POSTs the queued on_search callbacks to their bap_uri and returns how many
+// were accepted. Callbacks are held until now so that they never overtake the ACK they follow.
+func (b *BPP) SendCallbacks() (int, error) {
+	b.mu.Lock()
+	queued := b.queued
+	b.queued = nil
+	b.mu.Unlock()
+
+	delivered := 0
+	for _, callback := range queued {
+		body, err := json.Marshal(callback)
+		if err != nil {
+			return delivered, err
+		}
+		resp, err := http.Post(strings.TrimSuffix(callback.Context.BapURI, "/")+"/on_search", "application/json", bytes.NewReader(body))
+		if err != nil {
+			return delivered, err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return delivered, fmt.Errorf("on_search from %s rejected with status %d", callback.Context.BppID, resp.StatusCode)
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+func (b *BPP) handleSearch(w http.ResponseWriter, req *http.Request) {
+	sellerID, action, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+	if action != "search" {
+		http.NotFound(w, req)
+		return
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var payload broadcast.SearchPayload
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Context == nil {
+		http.Error(w, "invalid /search body", http.StatusBadRequest)
+		return
+	}
+
+	b.mu.Lock()
+	authorization := req.Header.Get(crypto.HeaderAuthorization)
+	sigErr := verifySignature(b.trustedKeys, authorization, body)
+	b.calls = append(b.calls, SearchCall{SellerID: sellerID, Authorization: authorization, SignatureError: sigErr, Payload: payload})
+	if sigErr != nil {
+		b.mu.Unlock()
+		http.Error(w, sigErr.Error(), http.StatusUnauthorized)
+		return
+	}
+	behaviour, ok := b.behaviours[sellerID]
+	if !ok {
+		behaviour = BPPAck
+	}
+	reply, sendReply := b.replies[sellerID]
+	b.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch behaviour {
+	case BPPHang:
+		select {
+		case <-req.Context().Done():
+		case <-b.stop:
+		}
+	case BPPServerError:
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	case BPPNack:
+		json.NewEncoder(w).Encode(broadcast.NackResponse{
+			Context: payload.Context,
+			Message: &broadcast.AckMessage{Ack: &broadcast.Ack{Status: "NACK"}},
+			Error:   &broadcast.Error{Type: "POLICY-ERROR", Code: "30000", Message: "bap not allowed"},
+		})
+	default:
+		json.NewEncoder(w).Encode(broadcast.AckResponse{
+			Context: payload.Context,
+			Message: &broadcast.AckMessage{Ack: &broadcast.Ack{Status: "ACK"}},
+		})
+		if sendReply {
+			b.mu.Lock()
+			b.queued = append(b.queued, b.onSearch(sellerID, *payload.Context, reply))
+			b.mu.Unlock()
+		}
+	}
+}
+
+func (b *BPP) onSearch(sellerID string, searchContext broadcast.Context, reply OnSearchReply) broadcast.OnSearchRequest {
+	searchContext.Action = "on_search"
+	searchContext.BppID = sellerID
+	searchContext.BppURI = b.SubscriberURL(sellerID)
+	callback := broadcast.OnSearchRequest{Context: &searchContext}
+	if reply.ErrorCode != "" {
+		callback.Error = &broadcast.Error{Type: "DOMAIN-ERROR", Code: reply.ErrorCode, Message: reply.ErrorMessage}
+		return callback
+	}
+	catalog := &broadcast.Catalog{}
+	if reply.Decision != "" {
+		catalog.Tags = []*broadcast.Tag{{
+			Code: "bap_access",
+			List: []*broadcast.List{{Code: "decision", Value: reply.Decision}, {Code: "reason", Value: reply.Reason}},
+		}}
+	}
+	callback.Message = &broadcast.OnSearchMessage{Catalog: catalog}
+	return callback
+}
+
+// NewOnSearchReceiver starts a server standing in for the BAP's /on_search endpoint; its URL
+// is the bap_uri to put in search contexts. Each callback is passed to handle.
+func NewOnSearchReceiver(t testing.TB, handle func(broadcast.OnSearchRequest) error) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var callback broadcast.OnSearchRequest
+		if err := json.NewDecoder(req.Body).Decode(&callback); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := handle(callback); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
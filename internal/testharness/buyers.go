@@ -0,0 +1,529 @@
+package testharness
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"adapter/internal/ports/broadcast"
+	buyerPorts "adapter/internal/ports/buyer"
+	sellerPorts "adapter/internal/ports/seller"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PermissionsRepository is an in-memory buyerPorts.PermissionsRepository. Queries that join
+// the sellers table read from sellers. Methods taking a context fail once it is done, as the
+// gorm repository would.
+type PermissionsRepository struct {
+	sellers *SellerRepository
+
+	mu          sync.Mutex
+	baps        map[string]buyerPorts.Bap
+	policies    map[string]buyerPorts.BapAccessPolicy
+	jobs        map[uuid.UUID]*buyerPorts.PermissionsJob
+	targets     map[uuid.UUID]map[string]*buyerPorts.PermissionsJobTarget
+	onSearch    []buyerPorts.OnSearchResponse
+	deliveries  []buyerPorts.WebhookDelivery
+	jobSequence time.Duration
+}
+
+var _ buyerPorts.PermissionsRepository = (*PermissionsRepository)(nil)
+
+func NewPermissionsRepository(sellers *SellerRepository) *PermissionsRepository {
+	return &PermissionsRepository{
+		sellers:  sellers,
+		baps:     make(map[string]buyerPorts.Bap),
+		policies: make(map[string]buyerPorts.BapAccessPolicy),
+		jobs:     make(map[uuid.UUID]*buyerPorts.PermissionsJob),
+		targets:  make(map[uuid.UUID]map[string]*buyerPorts.PermissionsJobTarget),
+	}
+}
+
+func policyKey(sellerID, domain, bapID string) string {
+	return sellerID + "|" + domain + "|" + bapID
+}
+
+// Policy returns a stored access policy, reporting whether it exists
+func (r *PermissionsRepository) Policy(sellerID, domain, bapID string) (buyerPorts.BapAccessPolicy, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	policy, ok := r.policies[policyKey(sellerID, domain, bapID)]
+	return policy, ok
+}
+
+// OnSearchResponses returns the recorded on_search callbacks
+func (r *PermissionsRepository) OnSearchResponses() []buyerPorts.OnSearchResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]buyerPorts.OnSearchResponse(nil), r.onSearch...)
+}
+
+func (r *PermissionsRepository) UpsertBaps(baps map[string]buyerPorts.Bap) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for id, bap := range baps {
+		if bap.FirstSeenAt == nil {
+			bap.FirstSeenAt = &now
+		}
+		if bap.LastSeenAt == nil {
+			bap.LastSeenAt = &now
+		}
+		if existing, ok := r.baps[id]; ok {
+			existing.LastSeenAt = bap.LastSeenAt
+			if existing.FirstSeenAt == nil {
+				existing.FirstSeenAt = bap.FirstSeenAt
+			}
+			r.baps[id] = existing
+			continue
+		}
+		bap.BapID = id
+		r.baps[id] = bap
+	}
+	return nil
+}
+
+func (r *PermissionsRepository) UpsertRegistryBaps(ctx context.Context, baps []buyerPorts.Bap) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, bap := range baps {
+		bap.FirstSeenAt, bap.LastSeenAt = nil, nil
+		if existing, ok := r.baps[bap.BapID]; ok {
+			bap.FirstSeenAt, bap.LastSeenAt = existing.FirstSeenAt, existing.LastSeenAt
+			if bap.KeepValidFrom {
+				bap.ValidFrom = existing.ValidFrom
+			}
+			if bap.KeepValidUntil {
+				bap.ValidUntil = existing.ValidUntil
+			}
+		}
+		bap.KeepValidFrom, bap.KeepValidUntil = false, false
+		r.baps[bap.BapID] = bap
+	}
+	return nil
+}
+
+func (r *PermissionsRepository) UpsertBapAccessPolicies(policies []buyerPorts.BapAccessPolicy) ([]bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	stored := make([]bool, len(policies))
+	for i, policy := range policies {
+		key := policyKey(policy.SellerID, policy.Domain, policy.BapID)
+		if existing, ok := r.policies[key]; ok && !replacesPolicy(existing, policy, now) {
+			continue
+		}
+		policy.LastProbedAt = r.policies[key].LastProbedAt
+		policy.UpdatedAt = now
+		r.policies[key] = policy
+		stored[i] = true
+	}
+	return stored, nil
+}
+
+// replacesPolicy mirrors the conflict condition of the gorm upsert
+func replacesPolicy(existing, incoming buyerPorts.BapAccessPolicy, now time.Time) bool {
+	expired := existing.ExpiresAt != nil && !existing.ExpiresAt.After(now)
+	if existing.DecisionSource == sellerPorts.SourceManualOverride && incoming.DecisionSource != sellerPorts.SourceManualOverride && !expired {
+		return false
+	}
+	return incoming.Decision != sellerPorts.DecisionPending || existing.Decision == sellerPorts.DecisionPending || expired
+}
+
+func (r *PermissionsRepository) FindBapByID(bapID string) (*buyerPorts.Bap, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	bap, ok := r.baps[bapID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &bap, nil
+}
+
+func (r *PermissionsRepository) QueryBapAccessPolicies(bapID, domain string, sellerIDs []string) ([]buyerPorts.BapAccessPolicy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var policies []buyerPorts.BapAccessPolicy
+	for _, sellerID := range sellerIDs {
+		if policy, ok := r.policies[policyKey(sellerID, domain, bapID)]; ok {
+			policies = append(policies, policy)
+		}
+	}
+	return policies, nil
+}
+
+func (r *PermissionsRepository) GetBapPolicy(bapID string) (*buyerPorts.BapAccessPolicy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, policy := range r.policies {
+		if policy.BapID == bapID {
+			return &policy, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *PermissionsRepository) GetExpiringBapAccessPolicies(ctx context.Context, query buyerPorts.ExpiringPolicyQuery) ([]buyerPorts.BapAccessPolicy, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	var policies []buyerPorts.BapAccessPolicy
+	for _, policy := range r.policies {
+		bap, ok := r.baps[policy.BapID]
+		seller, sellerOK := r.sellers.Seller(policy.SellerID, policy.Domain)
+		switch {
+		case !ok || bap.LastSeenAt == nil || bap.LastSeenAt.Before(query.ActiveSince):
+		case !sellerOK || !seller.Active:
+		case policy.ExpiresAt == nil || !policy.ExpiresAt.After(query.ExpiresAfter) || policy.ExpiresAt.After(query.ExpiresBefore):
+		case policy.LastProbedAt != nil && !policy.LastProbedAt.Before(query.ProbedBefore):
+		case policy.DecisionSource == sellerPorts.SourceManualOverride:
+		case policy.Decision == sellerPorts.DecisionPending && policy.ExpiresAt.After(now):
+		default:
+			policies = append(policies, policy)
+		}
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].ExpiresAt.Before(*policies[j].ExpiresAt) })
+	if len(policies) > query.Limit {
+		policies = policies[:query.Limit]
+	}
+	return policies, nil
+}
+
+func (r *PermissionsRepository) MarkBapAccessPoliciesProbed(ctx context.Context, bapID, domain string, sellerIDs []string, probedAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, sellerID := range sellerIDs {
+		key := policyKey(sellerID, domain, bapID)
+		if policy, ok := r.policies[key]; ok {
+			policy.LastProbedAt = &probedAt
+			r.policies[key] = policy
+		}
+	}
+	return nil
+}
+
+func (r *PermissionsRepository) CreatePermissionsJob(job *buyerPorts.PermissionsJob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job.ID = uuid.New()
+	// Jobs created in the same instant still claim in creation order.
+	r.jobSequence++
+	job.CreatedAt = time.Now().Add(r.jobSequence)
+	job.UpdatedAt = job.CreatedAt
+	stored := *job
+	r.jobs[job.ID] = &stored
+	return nil
+}
+
+func (r *PermissionsRepository) UpdatePermissionsJobStatus(jobID uuid.UUID, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[jobID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	job.Status = status
+	job.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *PermissionsRepository) FinishPermissionsJob(jobID uuid.UUID, status string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[jobID]
+	if !ok || (job.Status != buyerPorts.JobStatusInitiated && job.Status != buyerPorts.JobStatusRunning) {
+		return false, nil
+	}
+	now := time.Now()
+	job.Status = status
+	job.FinishedAt = &now
+	job.LockedBy, job.LockedUntil = nil, nil
+	return true, nil
+}
+
+func (r *PermissionsRepository) ClaimPermissionsJob(workerID string, lease time.Duration) (*buyerPorts.PermissionsJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	var claimed *buyerPorts.PermissionsJob
+	for _, job := range r.jobs {
+		claimable := job.Status == buyerPorts.JobStatusInitiated ||
+			(job.Status == buyerPorts.JobStatusRunning && (job.LockedUntil == nil || job.LockedUntil.Before(now)))
+		if claimable && (claimed == nil || job.CreatedAt.Before(claimed.CreatedAt)) {
+			claimed = job
+		}
+	}
+	if claimed == nil {
+		return nil, nil
+	}
+	lockedUntil := now.Add(lease)
+	claimed.Status = buyerPorts.JobStatusRunning
+	claimed.LockedBy = &workerID
+	claimed.LockedUntil = &lockedUntil
+	if claimed.StartedAt == nil {
+		claimed.StartedAt = &now
+	}
+	job := *claimed
+	return &job, nil
+}
+
+func (r *PermissionsRepository) RenewPermissionsJobLease(jobID uuid.UUID, workerID string, lease time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[jobID]
+	if !ok || job.LockedBy == nil || *job.LockedBy != workerID {
+		return buyerPorts.ErrJobLeaseLost
+	}
+	lockedUntil := time.Now().Add(lease)
+	job.LockedUntil = &lockedUntil
+	return nil
+}
+
+func (r *PermissionsRepository) GetPermissionsJobByID(jobID uuid.UUID) (*buyerPorts.PermissionsJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	job, ok := r.jobs[jobID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *job
+	return &copied, nil
+}
+
+func (r *PermissionsRepository) GetPermissionsJobByTransaction(transactionID, messageID string) (*buyerPorts.PermissionsJob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found *buyerPorts.PermissionsJob
+	for _, job := range r.jobs {
+		if job.TransactionID != transactionID || (messageID != "" && job.MessageID != messageID) {
+			continue
+		}
+		if found == nil || job.CreatedAt.After(found.CreatedAt) {
+			found = job
+		}
+	}
+	if found == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copied := *found
+	return &copied, nil
+}
+
+func (r *PermissionsRepository) CreateOnSearchResponse(resp *buyerPorts.OnSearchResponse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	resp.ID = uuid.New()
+	r.onSearch = append(r.onSearch, *resp)
+	return nil
+}
+
+func (r *PermissionsRepository) CreatePermissionsJobTargets(targets []buyerPorts.PermissionsJobTarget) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, target := range targets {
+		byJob := r.targets[target.JobID]
+		if byJob == nil {
+			byJob = make(map[string]*buyerPorts.PermissionsJobTarget)
+			r.targets[target.JobID] = byJob
+		}
+		if _, exists := byJob[target.SellerID]; exists {
+			continue
+		}
+		target.CreatedAt, target.UpdatedAt = now, now
+		stored := target
+		byJob[target.SellerID] = &stored
+	}
+	return nil
+}
+
+func (r *PermissionsRepository) GetPermissionsJobTarget(jobID uuid.UUID, sellerID string) (*buyerPorts.PermissionsJobTarget, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.targets[jobID][sellerID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	target := *stored
+	return &target, nil
+}
+
+func (r *PermissionsRepository) UpdatePermissionsJobTarget(target *buyerPorts.PermissionsJobTarget) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.targets[target.JobID][target.SellerID]
+	if !ok || target.Outcome == buyerPorts.TargetOutcomePending && stored.Outcome != buyerPorts.TargetOutcomePending {
+		return nil
+	}
+	stored.URL = target.URL
+	stored.Outcome = target.Outcome
+	stored.HTTPStatus = target.HTTPStatus
+	stored.LatencyMs = target.LatencyMs
+	if target.Decision != sellerPorts.DecisionPending || stored.Decision == "" || stored.Decision == sellerPorts.DecisionPending {
+		stored.Decision = target.Decision
+	}
+	stored.Error = target.Error
+	stored.AttemptCount = target.AttemptCount
+	stored.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *PermissionsRepository) UpdatePermissionsJobTargetDecision(jobID uuid.UUID, sellerID string, decision sellerPorts.AccessDecision) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stored, ok := r.targets[jobID][sellerID]; ok {
+		stored.Decision = decision
+	}
+	return nil
+}
+
+func (r *PermissionsRepository) CountPermissionsJobTargets(jobID uuid.UUID) (map[buyerPorts.TargetOutcome]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[buyerPorts.TargetOutcome]int)
+	for _, target := range r.targets[jobID] {
+		counts[target.Outcome]++
+	}
+	return counts, nil
+}
+
+func (r *PermissionsRepository) GetPermissionsJobAttemptStats(jobID uuid.UUID) (int, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	attempts, retried := 0, 0
+	for _, target := range r.targets[jobID] {
+		attempts += target.AttemptCount
+		if target.AttemptCount > 1 {
+			retried++
+		}
+	}
+	return attempts, retried, nil
+}
+
+func (r *PermissionsRepository) ListPermissionsJobTargets(jobID uuid.UUID, outcome string, limit, offset int) ([]buyerPorts.PermissionsJobTarget, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var targets []buyerPorts.PermissionsJobTarget
+	for _, target := range r.targets[jobID] {
+		if outcome == "" || string(target.Outcome) == outcome {
+			targets = append(targets, *target)
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].SellerID < targets[j].SellerID })
+	return page(targets, limit, offset), nil
+}
+
+func (r *PermissionsRepository) GetPendingPermissionsJobTargets(jobID uuid.UUID) ([]buyerPorts.PermissionsJobTarget, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var targets []buyerPorts.PermissionsJobTarget
+	for _, target := range r.targets[jobID] {
+		if target.Outcome == buyerPorts.TargetOutcomePending {
+			targets = append(targets, *target)
+		}
+	}
+	return targets, nil
+}
+
+func (r *PermissionsRepository) GetOpenPermissionsJobSellers(bapID, domain string, sellerIDs []string) (map[string]uuid.UUID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wanted := make(map[string]bool, len(sellerIDs))
+	for _, id := range sellerIDs {
+		wanted[id] = true
+	}
+	open := make(map[string]uuid.UUID)
+	for jobID, job := range r.jobs {
+		if job.BapID != bapID || (job.Status != buyerPorts.JobStatusInitiated && job.Status != buyerPorts.JobStatusRunning) {
+			continue
+		}
+		if len(r.targets[jobID]) > 0 {
+			for _, target := range r.targets[jobID] {
+				if target.Domain == domain && wanted[target.SellerID] {
+					open[target.SellerID] = jobID
+				}
+			}
+			continue
+		}
+		var req broadcast.BroadcastRequest
+		if err := json.Unmarshal([]byte(job.RequestPayload), &req); err != nil || req.SearchPayload == nil || req.SearchPayload.Context == nil {
+			continue
+		}
+		if req.SearchPayload.Context.Domain != domain {
+			continue
+		}
+		for _, sellerID := range req.SellerIDs {
+			if wanted[sellerID] {
+				open[sellerID] = jobID
+			}
+		}
+	}
+	return open, nil
+}
+
+func (r *PermissionsRepository) ClosePendingPermissionsJobTargets(jobID uuid.UUID, outcome buyerPorts.TargetOutcome, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, target := range r.targets[jobID] {
+		if target.Outcome == buyerPorts.TargetOutcomePending {
+			message := reason
+			target.Outcome = outcome
+			target.Error = &message
+		}
+	}
+	return nil
+}
+
+func (r *PermissionsRepository) CreateWebhookDelivery(delivery *buyerPorts.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery.ID = uuid.New()
+	delivery.CreatedAt = time.Now()
+	r.deliveries = append(r.deliveries, *delivery)
+	return nil
+}
+
+func (r *PermissionsRepository) DeleteFinishedPermissionsJobs(ctx context.Context, finishedBefore time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deleted int64
+	for id, job := range r.jobs {
+		if job.FinishedAt == nil || !job.FinishedAt.Before(finishedBefore) {
+			continue
+		}
+		delete(r.jobs, id)
+		delete(r.targets, id)
+		deleted++
+	}
+	onSearch := r.onSearch[:0]
+	for _, resp := range r.onSearch {
+		if _, ok := r.jobs[resp.JobID]; ok {
+			onSearch = append(onSearch, resp)
+		}
+	}
+	r.onSearch = onSearch
+	deliveries := r.deliveries[:0]
+	for _, delivery := range r.deliveries {
+		if _, ok := r.jobs[delivery.JobID]; ok {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	r.deliveries = deliveries
+	return deleted, nil
+}
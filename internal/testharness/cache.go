@@ -0,0 +1,53 @@
+package testharness
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"adapter/internal/shared/caching"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Cache is an in-memory caching.CacheService that round-trips values through JSON like the
+// Redis implementation does. Expirations are ignored.
+type Cache struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+var _ caching.CacheService = (*Cache)(nil)
+
+func NewCache() *Cache {
+	return &Cache{values: make(map[string][]byte)}
+}
+
+func (c *Cache) Get(_ context.Context, key string, dest interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.values[key]
+	if !ok {
+		return redis.Nil
+	}
+	return json.Unmarshal(value, dest)
+}
+
+func (c *Cache) Set(_ context.Context, key string, value interface{}, _ time.Duration) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = b
+	return nil
+}
+
+func (c *Cache) Delete(_ context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.values, key)
+	return nil
+}
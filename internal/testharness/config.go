@@ -0,0 +1,61 @@
+package testharness
+
+import (
+	"testing"
+	"time"
+
+	"adapter/internal/config"
+	"adapter/internal/shared/crypto"
+
+	"github.com/kelseyhightower/envconfig"
+)
+
+const (
+	SubscriberID = "adapter.test.example"
+	UniqueKeyID  = "test-key-1"
+	TestDomain   = "ONDC:RET10"
+)
+
+// NewConfig returns the default configuration with a fresh signing key and timings short
+// enough for tests. When registry is non-nil it becomes REGISTRY_URL and trusts the key, as
+// does every counterparty in trusting.
+func NewConfig(t testing.TB, registry *Registry, trusting ...KeyTruster) *config.Config {
+	t.Helper()
+	t.Setenv("DATABASE_URL", "unused")
+	t.Setenv("REDIS_URL", "unused")
+
+	cfg := &config.Config{}
+	if err := envconfig.Process("", cfg); err != nil {
+		t.Fatalf("load default config: %v", err)
+	}
+
+	publicKey, privateKey, err := crypto.NewONDCCrypto().GenerateSigningKeys()
+	if err != nil {
+		t.Fatalf("generate signing keys: %v", err)
+	}
+	cfg.SubscriberID = SubscriberID
+	cfg.UniqueKeyID = UniqueKeyID
+	cfg.PrivateKey = privateKey
+	cfg.Domains = []string{TestDomain}
+
+	cfg.BroadcastWorkers = 1
+	cfg.BroadcastPollInterval = 10 * time.Millisecond
+	cfg.BroadcastJobTimeout = 5 * time.Second
+	cfg.BroadcastHostRateLimit = 1000
+	cfg.BroadcastHostBurst = 1000
+	cfg.BroadcastRetryBaseDelay = time.Millisecond
+	cfg.BroadcastRetryMaxDelay = 5 * time.Millisecond
+	cfg.WebhookRetryBaseDelay = time.Millisecond
+	cfg.WebhookRetryMaxDelay = 5 * time.Millisecond
+	// Fake callback receivers listen on loopback.
+	cfg.WebhookAllowPrivateNetworks = true
+
+	if registry != nil {
+		cfg.RegistryURL = registry.URL()
+		registry.Trust(SubscriberID, UniqueKeyID, publicKey)
+	}
+	for _, counterparty := range trusting {
+		counterparty.Trust(SubscriberID, UniqueKeyID, publicKey)
+	}
+	return cfg
+}
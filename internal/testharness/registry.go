@@ -0,0 +1,195 @@
+package testharness
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"adapter/internal/shared/crypto"
+)
+
+// RegistryEntry is a subscriber record as served by the registry /lookup API
+type RegistryEntry struct {
+	SubscriberID  string `json:"subscriber_id"`
+	SubscriberURL string `json:"subscriber_url"`
+	Type          string `json:"type"`
+	UkID          string `json:"ukId"`
+	BrID          string `json:"br_id"`
+	Domain        string `json:"domain"`
+	Country       string `json:"country"`
+	City          string `json:"city"`
+	SigningKey    string `json:"signing_public_key"`
+	EncryptionKey string `json:"encr_public_key"`
+	Status        string `json:"status"`
+	ValidFrom     string `json:"valid_from"`
+	ValidUntil    string `json:"valid_until"`
+	Created       string `json:"created"`
+	Updated       string `json:"updated"`
+}
+
+// LookupRequest is a /lookup request received by the fake registry
+type LookupRequest struct {
+	Country      string `json:"country"`
+	Type         string `json:"type"`
+	Domain       string `json:"domain"`
+	City         string `json:"city"`
+	SubscriberID string `json:"subscriber_id"`
+	UkID         string `json:"ukId"`
+	Page         int    `json:"page"`
+	Limit        int    `json:"limit"`
+}
+
+// Registry is a fake ONDC registry. /lookup requests must carry a valid signature from a
+// trusted key unless signatures are disabled, and are answered from the configured entries.
+type Registry struct {
+	Server *httptest.Server
+
+	mu               sync.Mutex
+	entries          []RegistryEntry
+	trustedKeys      map[string]string
+	requireSignature bool
+	wrapResponse     bool
+	ignorePaging     bool
+	failStatus       int
+	lookups          []LookupRequest
+}
+
+// NewRegistry starts a fake registry that is shut down when the test ends
+func NewRegistry(t testing.TB) *Registry {
+	r := &Registry{trustedKeys: make(map[string]string), requireSignature: true}
+	r.Server = httptest.NewServer(http.HandlerFunc(r.handleLookup))
+	t.Cleanup(r.Server.Close)
+	return r
+}
+
+// URL is the lookup endpoint to configure as REGISTRY_URL
+func (r *Registry) URL() string {
+	return r.Server.URL + "/lookup"
+}
+
+// Trust accepts lookups signed with publicKey under subscriberID and ukID
+func (r *Registry) Trust(subscriberID, ukID, publicKey string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trustedKeys[subscriberID+"|"+ukID] = publicKey
+}
+
+// RequireSignature toggles signature checks, as v1 registries serve /lookup unauthenticated
+func (r *Registry) RequireSignature(required bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requireSignature = required
+}
+
+// WrapResponse answers with {"subscribers": [...]} instead of a plain array
+func (r *Registry) WrapResponse(wrap bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.wrapResponse = wrap
+}
+
+// IgnorePaging answers every page with all matching entries, like registries without paging
+func (r *Registry) IgnorePaging(ignore bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ignorePaging = ignore
+}
+
+// FailWith makes every lookup answer with the given HTTP status; 0 restores normal answers
+func (r *Registry) FailWith(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failStatus = status
+}
+
+// SetEntries replaces the subscribers served by the registry
+func (r *Registry) SetEntries(entries ...RegistryEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append([]RegistryEntry(nil), entries...)
+}
+
+// Lookups returns the lookup requests received so far
+func (r *Registry) Lookups() []LookupRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]LookupRequest(nil), r.lookups...)
+}
+
+func (r *Registry) handleLookup(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.requireSignature {
+		if err := r.verify(req.Header.Get(crypto.HeaderAuthorization), body); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var lookup LookupRequest
+	if err := json.Unmarshal(body, &lookup); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.lookups = append(r.lookups, lookup)
+
+	if r.failStatus != 0 {
+		http.Error(w, "registry unavailable", r.failStatus)
+		return
+	}
+
+	matches := []RegistryEntry{}
+	for _, entry := range r.entries {
+		if matchesLookup(entry, lookup) {
+			matches = append(matches, entry)
+		}
+	}
+	if lookup.Limit > 0 && !r.ignorePaging {
+		start := (max(lookup.Page, 1) - 1) * lookup.Limit
+		end := min(start+lookup.Limit, len(matches))
+		if start >= len(matches) {
+			matches = []RegistryEntry{}
+		} else {
+			matches = matches[start:end]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.wrapResponse {
+		json.NewEncoder(w).Encode(map[string]interface{}{"subscribers": matches})
+		return
+	}
+	json.NewEncoder(w).Encode(matches)
+}
+
+func (r *Registry) verify(header string, body []byte) error {
+	return verifySignature(r.trustedKeys, header, body)
+}
+
+func matchesLookup(entry RegistryEntry, lookup LookupRequest) bool {
+	filters := [][2]string{
+		{entry.Country, lookup.Country},
+		{entry.Type, lookup.Type},
+		{entry.Domain, lookup.Domain},
+		{entry.City, lookup.City},
+		{entry.SubscriberID, lookup.SubscriberID},
+		{entry.UkID, lookup.UkID},
+	}
+	for _, f := range filters {
+		if f[1] != "" && !strings.EqualFold(f[0], f[1]) {
+			return false
+		}
+	}
+	return true
+}
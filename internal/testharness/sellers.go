@@ -0,0 +1,352 @@
+package testharness
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	sellerPorts "adapter/internal/ports/seller"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SellerRepository is an in-memory sellerPorts.SellerRepository. Filters and pagination follow
+// the gorm repository closely enough for service tests, and methods taking a context fail once
+// it is done.
+type SellerRepository struct {
+	mu            sync.Mutex
+	sellers       map[string]sellerPorts.Seller
+	catalogStates map[string]sellerPorts.SellerCatalogState
+	changes       []sellerPorts.SellerChange
+	runs          []sellerPorts.RegistrySyncRun
+	nextChangeID  int64
+}
+
+var _ sellerPorts.SellerRepository = (*SellerRepository)(nil)
+
+func NewSellerRepository() *SellerRepository {
+	return &SellerRepository{
+		sellers:       make(map[string]sellerPorts.Seller),
+		catalogStates: make(map[string]sellerPorts.SellerCatalogState),
+	}
+}
+
+func sellerKey(sellerID, domain string) string {
+	return sellerID + "|" + domain
+}
+
+// Seller returns a stored seller, reporting whether it exists
+func (r *SellerRepository) Seller(sellerID, domain string) (sellerPorts.Seller, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seller, ok := r.sellers[sellerKey(sellerID, domain)]
+	return seller, ok
+}
+
+func (r *SellerRepository) InsertSellers(sellers []sellerPorts.Seller) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, seller := range sellers {
+		seller.CreatedAt, seller.UpdatedAt = now, now
+		r.sellers[sellerKey(seller.SellerID, seller.Domain)] = seller
+	}
+	return nil
+}
+
+func (r *SellerRepository) UpdateSellers(sellers []sellerPorts.Seller) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, seller := range sellers {
+		key := sellerKey(seller.SellerID, seller.Domain)
+		if existing, ok := r.sellers[key]; ok {
+			seller.CreatedAt = existing.CreatedAt
+		}
+		seller.UpdatedAt = time.Now()
+		r.sellers[key] = seller
+	}
+	return nil
+}
+
+func (r *SellerRepository) GetAllSellers() ([]sellerPorts.Seller, error) {
+	return r.GetSellersByFilters(nil)
+}
+
+// GetSellersByFilters supports the filters the services use: string and []string values match
+// case-insensitively, "city" also matches the "*" wildcard and other values match exactly.
+func (r *SellerRepository) GetSellersByFilters(filters map[string]interface{}) ([]sellerPorts.Seller, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sellers []sellerPorts.Seller
+	for _, seller := range r.sellers {
+		if matchesSellerFilters(seller, filters) {
+			sellers = append(sellers, seller)
+		}
+	}
+	sort.Slice(sellers, func(i, j int) bool { return sellers[i].SellerID < sellers[j].SellerID })
+	return sellers, nil
+}
+
+func matchesSellerFilters(seller sellerPorts.Seller, filters map[string]interface{}) bool {
+	fields := map[string]string{
+		"seller_id": seller.SellerID,
+		"domain":    seller.Domain,
+		"status":    seller.Status,
+		"type":      seller.Type,
+		"country":   seller.Country,
+		"city":      seller.City,
+	}
+	for key, value := range filters {
+		switch v := value.(type) {
+		case string:
+			if key == "city" && seller.City == "*" {
+				continue
+			}
+			if !strings.EqualFold(fields[key], v) {
+				return false
+			}
+		case []string:
+			found := false
+			for _, candidate := range v {
+				if strings.EqualFold(fields[key], candidate) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		case bool:
+			if key == "active" && seller.Active != v {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (r *SellerRepository) GetPendingSellers(domain, status string, limit, offset int) ([]sellerPorts.SellerInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	wanted := map[string]bool{}
+	for _, s := range strings.Split(status, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			wanted[s] = true
+		}
+	}
+	if len(wanted) == 0 {
+		wanted[string(sellerPorts.CatalogStatusNotSynced)] = true
+		wanted[string(sellerPorts.CatalogStatusFailed)] = true
+	}
+
+	var infos []sellerPorts.SellerInfo
+	for _, seller := range r.sellers {
+		if seller.Domain != domain || !seller.Active {
+			continue
+		}
+		info := sellerPorts.SellerInfo{SellerID: seller.SellerID, Status: string(sellerPorts.CatalogStatusNotSynced)}
+		if state, ok := r.catalogStates[sellerKey(seller.SellerID, seller.Domain)]; ok {
+			info.Status = string(state.Status)
+			info.LastPullAt, info.LastSuccessAt, info.LastError = state.LastPullAt, state.LastSuccessAt, state.LastError
+		}
+		if wanted[info.Status] {
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].SellerID < infos[j].SellerID })
+	return page(infos, limit, offset), nil
+}
+
+func (r *SellerRepository) DeactivateSellers(sellerIDs []string, domain string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, id := range sellerIDs {
+		key := sellerKey(id, domain)
+		if seller, ok := r.sellers[key]; ok {
+			seller.Active = false
+			r.sellers[key] = seller
+		}
+	}
+	return nil
+}
+
+func (r *SellerRepository) ReconcileDomainSellers(ctx context.Context, rec sellerPorts.DomainReconciliation) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, seller := range rec.Upserts {
+		key := sellerKey(seller.SellerID, seller.Domain)
+		seller.CreatedAt = now
+		if existing, ok := r.sellers[key]; ok {
+			seller.CreatedAt = existing.CreatedAt
+		}
+		seller.UpdatedAt = now
+		r.sellers[key] = seller
+	}
+	for _, id := range rec.UnchangedSellerIDs {
+		key := sellerKey(id, rec.Domain)
+		if seller, ok := r.sellers[key]; ok {
+			seller.LastSeenInReg = rec.SeenAt
+			r.sellers[key] = seller
+		}
+	}
+	for _, id := range rec.NewSellerIDs {
+		key := sellerKey(id, rec.Domain)
+		if _, ok := r.catalogStates[key]; !ok {
+			r.catalogStates[key] = sellerPorts.SellerCatalogState{SellerID: id, Domain: rec.Domain, Status: sellerPorts.CatalogStatusNotSynced}
+		}
+	}
+	for _, id := range rec.RemovedSellerIDs {
+		key := sellerKey(id, rec.Domain)
+		if seller, ok := r.sellers[key]; ok {
+			seller.Active = false
+			r.sellers[key] = seller
+		}
+	}
+	for _, change := range rec.Changes {
+		r.nextChangeID++
+		change.ID = r.nextChangeID
+		r.changes = append(r.changes, change)
+	}
+	return nil
+}
+
+func (r *SellerRepository) GetSellersWithInvalidURL(domain string, limit, offset int) ([]sellerPorts.Seller, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sellers []sellerPorts.Seller
+	for _, seller := range r.sellers {
+		if seller.Active && seller.URLError != nil && (domain == "" || seller.Domain == domain) {
+			sellers = append(sellers, seller)
+		}
+	}
+	sort.Slice(sellers, func(i, j int) bool {
+		if sellers[i].Domain != sellers[j].Domain {
+			return sellers[i].Domain < sellers[j].Domain
+		}
+		return sellers[i].SellerID < sellers[j].SellerID
+	})
+	return page(sellers, limit, offset), nil
+}
+
+func (r *SellerRepository) ListSellerChanges(sellerID, domain string, limit, offset int) ([]sellerPorts.SellerChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var changes []sellerPorts.SellerChange
+	for i := len(r.changes) - 1; i >= 0; i-- {
+		change := r.changes[i]
+		if change.SellerID == sellerID && (domain == "" || change.Domain == domain) {
+			changes = append(changes, change)
+		}
+	}
+	return page(changes, limit, offset), nil
+}
+
+func (r *SellerRepository) ListRegistrySyncRunChanges(runID uuid.UUID, limit, offset int) ([]sellerPorts.SellerChange, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var changes []sellerPorts.SellerChange
+	for _, change := range r.changes {
+		if change.RunID != nil && *change.RunID == runID {
+			changes = append(changes, change)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Domain != changes[j].Domain {
+			return changes[i].Domain < changes[j].Domain
+		}
+		if changes[i].SellerID != changes[j].SellerID {
+			return changes[i].SellerID < changes[j].SellerID
+		}
+		return changes[i].ID < changes[j].ID
+	})
+	return page(changes, limit, offset), nil
+}
+
+func (r *SellerRepository) UpsertCatalogState(state *sellerPorts.SellerCatalogState) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state.UpdatedAt = time.Now()
+	r.catalogStates[sellerKey(state.SellerID, state.Domain)] = *state
+	return nil
+}
+
+func (r *SellerRepository) GetSellerCatalogState(sellerID, domain string) (*sellerPorts.SellerCatalogState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, ok := r.catalogStates[sellerKey(sellerID, domain)]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &state, nil
+}
+
+func (r *SellerRepository) CreateRegistrySyncRun(run *sellerPorts.RegistrySyncRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run.ID = uuid.New()
+	stored := *run
+	stored.Domains = nil
+	r.runs = append(r.runs, stored)
+	return nil
+}
+
+func (r *SellerRepository) FinishRegistrySyncRun(run *sellerPorts.RegistrySyncRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.runs {
+		if r.runs[i].ID != run.ID {
+			continue
+		}
+		r.runs[i].FinishedAt = run.FinishedAt
+		r.runs[i].Status = run.Status
+		r.runs[i].MalformedRecords = run.MalformedRecords
+		r.runs[i].Domains = make([]sellerPorts.RegistrySyncRunDomain, len(run.Domains))
+		for j, domain := range run.Domains {
+			domain.RunID = run.ID
+			r.runs[i].Domains[j] = domain
+		}
+		return nil
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *SellerRepository) ListRegistrySyncRuns(limit, offset int) ([]sellerPorts.RegistrySyncRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	runs := make([]sellerPorts.RegistrySyncRun, 0, len(r.runs))
+	for i := len(r.runs) - 1; i >= 0; i-- {
+		runs = append(runs, r.runs[i])
+	}
+	return page(runs, limit, offset), nil
+}
+
+func (r *SellerRepository) GetRegistrySyncRunByID(runID uuid.UUID) (*sellerPorts.RegistrySyncRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, run := range r.runs {
+		if run.ID == runID {
+			return &run, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// page applies offset and limit, returning one extra record like the gorm repositories do
+// for their hasMore checks.
+func page[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit >= 0 && len(items) > limit+1 {
+		items = items[:limit+1]
+	}
+	return items
+}
@@ -0,0 +1,46 @@
+package testharness
+
+import (
+	"time"
+
+	"adapter/internal/shared/crypto"
+)
+
+// KeyTruster is a fake counterparty that checks ONDC signatures against the keys it trusts
+type KeyTruster interface {
+	Trust(subscriberID, ukID, publicKey string)
+}
+
+// verifySignature checks an ONDC Authorization header against body, looking the signing key up
+// in trustedKeys by "subscriber_id|ukId".
+func verifySignature(trustedKeys map[string]string, header string, body []byte) error {
+	if header == "" {
+		return errUnauthorized("missing Authorization header")
+	}
+	sig, err := crypto.ParseSignatureHeader(header)
+	if err != nil {
+		return err
+	}
+	publicKey, ok := trustedKeys[sig.SubscriberID+"|"+sig.UniqueKeyID]
+	if !ok {
+		return errUnauthorized("unknown key " + sig.SubscriberID + "|" + sig.UniqueKeyID)
+	}
+	now := time.Now().Unix()
+	if int64(sig.Created) > now+5 || int64(sig.Expires) < now {
+		return errUnauthorized("signature outside its validity window")
+	}
+	valid, err := crypto.NewONDCCrypto().VerifyRequest(publicKey, body, sig.Created, sig.Expires, sig.Signature)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return errUnauthorized("signature does not match body")
+	}
+	return nil
+}
+
+type errUnauthorized string
+
+func (e errUnauthorized) Error() string {
+	return string(e)
+}